@@ -7,6 +7,8 @@ type App struct {
 	Owner   string `json:"owner"`
 	Updated string `json:"updated"`
 	UUID    string `json:"uuid"`
+	// Structure is the number of processes of each type the app is scaled to.
+	Structure map[string]int `json:"structure,omitempty"`
 }
 
 // Apps defines a collection of app objects.
@@ -7,10 +7,10 @@ import (
 
 func TestAppsSorted(t *testing.T) {
 	apps := Apps{
-		{"2014-01-01T00:00:00UTC", "Zulu", "John", "2016-01-02", "d57be2ba-7ae2-4825-9ace-7c86cb893046"},
-		{"2014-01-01T00:00:00UTC", "Alpha", "John", "2016-01-02", "3d501190-1b8e-41ef-94c5-dd9a0bb707bb"},
-		{"2014-01-01T00:00:00UTC", "Gamma", "John", "2016-01-02", "41d95133-fd4d-4f4c-92a2-e454857371cc"},
-		{"2014-01-01T00:00:00UTC", "Beta", "John", "2016-01-02", "222ed1aa-e985-4bec-9966-a88215300661"},
+		{Created: "2014-01-01T00:00:00UTC", ID: "Zulu", Owner: "John", Updated: "2016-01-02", UUID: "d57be2ba-7ae2-4825-9ace-7c86cb893046"},
+		{Created: "2014-01-01T00:00:00UTC", ID: "Alpha", Owner: "John", Updated: "2016-01-02", UUID: "3d501190-1b8e-41ef-94c5-dd9a0bb707bb"},
+		{Created: "2014-01-01T00:00:00UTC", ID: "Gamma", Owner: "John", Updated: "2016-01-02", UUID: "41d95133-fd4d-4f4c-92a2-e454857371cc"},
+		{Created: "2014-01-01T00:00:00UTC", ID: "Beta", Owner: "John", Updated: "2016-01-02", UUID: "222ed1aa-e985-4bec-9966-a88215300661"},
 	}
 
 	sort.Sort(apps)
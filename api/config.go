@@ -34,8 +34,8 @@ type Config struct {
 	Healthcheck map[string]*Healthchecks `json:"healthcheck,omitempty"`
 	// Tags restrict applications to run on k8s nodes with that label.
 	Tags map[string]interface{} `json:"tags,omitempty"`
-	// Registry is a key-value pair to provide authentication for docker registries.
-	// The key is the username and the value is the password.
+	// Registry provides authentication for private docker registries. Its keys are "username"
+	// and "password". See RegistryCredentials.
 	Registry map[string]interface{} `json:"registry,omitempty"`
 	// Created is the time that the application was created and cannot be updated.
 	Created string `json:"created,omitempty"`
@@ -46,6 +46,38 @@ type Config struct {
 	UUID string `json:"uuid,omitempty"`
 }
 
+// RegistryCredentials are the credentials of an app's private docker registry.
+// Printing them with fmt masks the password.
+type RegistryCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RegistryCredentials returns the config's registry credentials.
+func (c Config) RegistryCredentials() RegistryCredentials {
+	username, _ := c.Registry["username"].(string)
+	password, _ := c.Registry["password"].(string)
+	return RegistryCredentials{Username: username, Password: password}
+}
+
+// Empty returns true if no username is set.
+func (r RegistryCredentials) Empty() bool {
+	return r.Username == ""
+}
+
+// String displays the RegistryCredentials with the password masked.
+func (r RegistryCredentials) String() string {
+	if r.Password == "" {
+		return r.Username
+	}
+	return r.Username + ":********"
+}
+
+// GoString displays the RegistryCredentials with the password masked.
+func (r RegistryCredentials) GoString() string {
+	return fmt.Sprintf("api.RegistryCredentials{Username:%q, Password:\"********\"}", r.Username)
+}
+
 // ConfigHookRequest defines the request for configuration from the config hook.
 type ConfigHookRequest struct {
 	User string `json:"receive_user"`
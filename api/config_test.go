@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -59,3 +60,18 @@ TCP Socket Probe: Port=80`)
 		t.Errorf("Expected:\n\n%s\n\nGot:\n\n%s", expected, h.String())
 	}
 }
+
+func TestRegistryCredentialsMasked(t *testing.T) {
+	creds := Config{Registry: map[string]interface{}{"username": "deis", "password": "secret"}}.RegistryCredentials()
+
+	if creds.Username != "deis" || creds.Password != "secret" {
+		t.Errorf("Expected deis and secret, Got %s and %s", creds.Username, creds.Password)
+	}
+
+	wrapped := struct{ Creds RegistryCredentials }{creds}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if actual := fmt.Sprintf(format, wrapped); strings.Contains(actual, "secret") {
+			t.Errorf("Expected the password to be masked, Got %s", actual)
+		}
+	}
+}
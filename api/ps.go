@@ -36,3 +36,75 @@ type PodTypes []PodType
 func (p PodTypes) Len() int           { return len(p) }
 func (p PodTypes) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 func (p PodTypes) Less(i, j int) bool { return p[i].Type < p[j].Type }
+
+// PodState is the state of a pod, as reported by the scheduler.
+type PodState int
+
+const (
+	// PodUnknown is a state that is not recognized by the SDK.
+	PodUnknown PodState = iota
+	// PodInitializing is a pod that has been scheduled but not yet created.
+	PodInitializing
+	// PodCreating is a pod whose containers are being created.
+	PodCreating
+	// PodStarting is a pod whose containers are starting.
+	PodStarting
+	// PodUp is a pod which is running and ready.
+	PodUp
+	// PodTerminating is a pod which is shutting down.
+	PodTerminating
+	// PodDown is a pod which has stopped.
+	PodDown
+	// PodDestroyed is a pod which has been removed.
+	PodDestroyed
+	// PodCrashed is a pod whose containers have crashed.
+	PodCrashed
+	// PodError is a pod which failed to start.
+	PodError
+)
+
+var podStates = []string{
+	"unknown",
+	"initializing",
+	"creating",
+	"starting",
+	"up",
+	"terminating",
+	"down",
+	"destroyed",
+	"crashed",
+	"error",
+}
+
+// ParsePodState parses the free-form State of a pod. Unrecognized states are PodUnknown.
+func ParsePodState(state string) PodState {
+	for i, s := range podStates {
+		if s == state {
+			return PodState(i)
+		}
+	}
+	return PodUnknown
+}
+
+func (s PodState) String() string {
+	if s < 0 || int(s) >= len(podStates) {
+		return podStates[PodUnknown]
+	}
+	return podStates[s]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s PodState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (s *PodState) UnmarshalText(text []byte) error {
+	*s = ParsePodState(string(text))
+	return nil
+}
+
+// Failed returns true if the pod has crashed or failed to start.
+func (s PodState) Failed() bool {
+	return s == PodCrashed || s == PodError
+}
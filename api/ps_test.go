@@ -42,3 +42,32 @@ func TestPodTypesSorted(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePodState(t *testing.T) {
+	tests := map[string]PodState{
+		"up":          PodUp,
+		"starting":    PodStarting,
+		"crashed":     PodCrashed,
+		"error":       PodError,
+		"terminating": PodTerminating,
+		"Up":          PodUnknown,
+		"exploded":    PodUnknown,
+		"":            PodUnknown,
+	}
+
+	for state, expected := range tests {
+		if actual := ParsePodState(state); actual != expected {
+			t.Errorf("Expected %s to be %v, Got %v", state, expected, actual)
+		}
+	}
+
+	for _, state := range podStates {
+		if actual := ParsePodState(state).String(); actual != state {
+			t.Errorf("Expected %s, Got %s", state, actual)
+		}
+	}
+
+	if !PodCrashed.Failed() || !PodError.Failed() || PodUp.Failed() {
+		t.Error("Expected only crashed and error states to be failed")
+	}
+}
@@ -162,11 +162,12 @@ func TestAppsCreate(t *testing.T) {
 	defer server.Close()
 
 	expected := api.App{
-		ID:      "example-go",
-		Created: "2014-01-01T00:00:00UTC",
-		Owner:   "test",
-		Updated: "2014-01-01T00:00:00UTC",
-		UUID:    "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+		ID:        "example-go",
+		Created:   "2014-01-01T00:00:00UTC",
+		Owner:     "test",
+		Updated:   "2014-01-01T00:00:00UTC",
+		UUID:      "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+		Structure: map[string]int{},
 	}
 
 	deis, err := deis.New(false, server.URL, "abc")
@@ -195,11 +196,12 @@ func TestAppsGet(t *testing.T) {
 	defer server.Close()
 
 	expected := api.App{
-		ID:      "example-go",
-		Created: "2014-01-01T00:00:00UTC",
-		Owner:   "test",
-		Updated: "2014-01-01T00:00:00UTC",
-		UUID:    "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+		ID:        "example-go",
+		Created:   "2014-01-01T00:00:00UTC",
+		Owner:     "test",
+		Updated:   "2014-01-01T00:00:00UTC",
+		UUID:      "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+		Structure: map[string]int{},
 	}
 
 	deis, err := deis.New(false, server.URL, "abc")
@@ -272,11 +274,12 @@ func TestAppsList(t *testing.T) {
 
 	expected := api.Apps{
 		{
-			ID:      "example-go",
-			Created: "2014-01-01T00:00:00UTC",
-			Owner:   "test",
-			Updated: "2014-01-01T00:00:00UTC",
-			UUID:    "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+			ID:        "example-go",
+			Created:   "2014-01-01T00:00:00UTC",
+			Owner:     "test",
+			Updated:   "2014-01-01T00:00:00UTC",
+			UUID:      "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+			Structure: map[string]int{},
 		},
 	}
 
@@ -36,6 +36,7 @@ func List(c *deis.Client, app string) (api.AppSettings, error) {
 //    - If the variable was ignored in the api.AppSettings, it will remain unchanged.
 //
 // Calling Set() with an empty api.AppSettings will return a deis.ErrConflict.
+// Use SetIfUnmodified or Update to avoid overwriting concurrent changes.
 func Set(c *deis.Client, app string, appSettings api.AppSettings) (api.AppSettings, error) {
 	body, err := json.Marshal(appSettings)
 
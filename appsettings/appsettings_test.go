@@ -310,3 +310,120 @@ func TestAppSettingsInvalidJson(t *testing.T) {
 		t.Errorf("Expected %v, Got %v", expected, err)
 	}
 }
+
+// versionedServer serves settings whose UUID changes on every change. Another client changes
+// the settings just before the reads numbered in concurrent.
+type versionedServer struct {
+	version    int
+	reads      int
+	concurrent map[int]bool
+	requests   []string
+}
+
+func (v *versionedServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	if req.Method == "GET" {
+		v.reads++
+		if v.concurrent[v.reads] {
+			v.version++
+		}
+	} else {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fmt.Println(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		v.requests = append(v.requests, string(body))
+		v.version++
+		res.WriteHeader(http.StatusCreated)
+	}
+
+	res.Write([]byte(fmt.Sprintf(`{"app": "example-go", "label": {"n": "%d"}, "updated": "2014-01-0%dT00:00:00UTC", "uuid": "uuid-%d"}`,
+		v.version, v.version+1, v.version)))
+}
+
+func TestAppSettingsSetIfUnmodified(t *testing.T) {
+	t.Parallel()
+
+	handler := &versionedServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := api.AppSettings{Maintenance: &trueVar}
+
+	if _, err := SetIfUnmodified(deis, "example-go", "uuid-0", patch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetIfUnmodified(deis, "example-go", "2014-01-02T00:00:00UTC", patch); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ErrModified{App: "example-go", Expected: "uuid-0", Actual: "uuid-2"}
+	if _, err := SetIfUnmodified(deis, "example-go", "uuid-0", patch); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+	if _, err := SetIfUnmodified(deis, "example-go", "", patch); err != ErrNoExpected {
+		t.Errorf("Expected %v, Got %v", ErrNoExpected, err)
+	}
+	if len(handler.requests) != 2 {
+		t.Errorf("Expected 2 changes, Got %v", handler.requests)
+	}
+}
+
+func TestAppSettingsUpdate(t *testing.T) {
+	t.Parallel()
+
+	handler := &versionedServer{concurrent: map[int]bool{2: true}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []interface{}
+	merge := func(current api.AppSettings) (api.AppSettings, error) {
+		seen = append(seen, current.Label["n"])
+		return api.AppSettings{Label: api.Labels{"m": current.Label["n"]}}, nil
+	}
+
+	settings, err := Update(deis, "example-go", 1, merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.UUID != "uuid-2" {
+		t.Errorf("Expected uuid-2, Got %s", settings.UUID)
+	}
+
+	expected := []interface{}{"0", "1"}
+	if !reflect.DeepEqual(expected, seen) {
+		t.Errorf("Expected %v, Got %v", expected, seen)
+	}
+	requests := []string{`{"label":{"m":"1"}}`}
+	if !reflect.DeepEqual(requests, handler.requests) {
+		t.Errorf("Expected %v, Got %v", requests, handler.requests)
+	}
+
+	handler.concurrent = map[int]bool{6: true}
+	_, err = Update(deis, "example-go", 0, merge)
+	if _, ok := err.(ErrModified); !ok {
+		t.Errorf("Expected ErrModified, Got %v", err)
+	}
+
+	reads := handler.reads
+	if _, err = Update(deis, "example-go", -1, merge); err != ErrNegativeRetries {
+		t.Errorf("Expected %v, Got %v", ErrNegativeRetries, err)
+	}
+	if handler.reads != reads {
+		t.Errorf("Expected no reads with negative retries, Got %d", handler.reads-reads)
+	}
+}
@@ -0,0 +1,97 @@
+package appsettings
+
+import (
+	"errors"
+	"fmt"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+var (
+	// ErrNoExpected is returned by SetIfUnmodified when no UUID or Updated time is expected.
+	ErrNoExpected = errors.New("No expected UUID or Updated time was given")
+	// ErrNegativeRetries is returned by Update when retries is less than zero.
+	ErrNegativeRetries = errors.New("The number of retries can't be negative")
+)
+
+// ErrModified is returned when an app's settings changed after they were read.
+type ErrModified struct {
+	App string
+	// Expected is the UUID or Updated time the settings were expected to have.
+	Expected string
+	// Actual is the UUID of the settings when they were read again.
+	Actual string
+}
+
+func (e ErrModified) Error() string {
+	return fmt.Sprintf("The settings of %s were modified after they were read: expected %s, found %s", e.App, e.Expected, e.Actual)
+}
+
+// SetIfUnmodified sets an app's settings like Set, unless they were modified since a List
+// returned them. Expected is the UUID or Updated time of the settings that List returned. The
+// settings are read again before they're set, and an ErrModified is returned if they changed.
+// An empty expected would match settings without a UUID or Updated time, so it returns
+// ErrNoExpected.
+//
+// A list setting such as the whitelist is replaced whole, so without the check an address
+// added concurrently would be dropped. The settings are compared by this client rather than
+// the controller, which still misses a change made between the two requests.
+func SetIfUnmodified(c *deis.Client, app string, expected string, appSettings api.AppSettings) (api.AppSettings, error) {
+	if expected == "" {
+		return api.AppSettings{}, ErrNoExpected
+	}
+
+	current, err := List(c, app)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.AppSettings{}, err
+	}
+	if expected != current.UUID && expected != current.Updated {
+		return api.AppSettings{}, ErrModified{App: app, Expected: expected, Actual: current.UUID}
+	}
+	return Set(c, app, appSettings)
+}
+
+// MergeFunc returns the patch to set on an app's settings, given their current state.
+type MergeFunc func(current api.AppSettings) (api.AppSettings, error)
+
+// Update reads an app's settings, calls merge with them, and sets the patch merge returns with
+// SetIfUnmodified. When the settings are modified in between, they're read again and merge is
+// called again, up to retries times, after which the ErrModified is returned. A negative
+// retries returns ErrNegativeRetries.
+//
+// This example adds an address to the whitelist without dropping one added concurrently:
+//
+//    _, err := appsettings.Update(client, "example-go", 3, func(current api.AppSettings) (api.AppSettings, error) {
+//        return api.AppSettings{
+//            Whitelist: append(current.Whitelist, "10.0.1.0/24"),
+//        }, nil
+//    })
+func Update(c *deis.Client, app string, retries int, merge MergeFunc) (api.AppSettings, error) {
+	if retries < 0 {
+		return api.AppSettings{}, ErrNegativeRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		current, err := List(c, app)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return api.AppSettings{}, err
+		}
+
+		expected := current.UUID
+		if expected == "" {
+			expected = current.Updated
+		}
+
+		patch, err := merge(current)
+		if err != nil {
+			return api.AppSettings{}, err
+		}
+
+		appSettings, err := SetIfUnmodified(c, app, expected, patch)
+		if _, ok := err.(ErrModified); ok && attempt < retries {
+			continue
+		}
+		return appSettings, err
+	}
+}
@@ -0,0 +1,261 @@
+// Package bluegreen provides blue/green deploys across a pair of apps.
+//
+// One app of the pair is live and serves the production domains while the other is idle.
+// Deploy deploys a build to the idle app, waits for it to roll out and then moves the domains
+// to it. The previously live app keeps running, so Switch can move the domains back at once.
+//
+// A domain can only belong to one app, so each domain is briefly unrouted while it moves.
+// If moving any domain fails, every domain moved so far is returned to the original app and
+// its certificate is re-attached.
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/controller-sdk-go/certs"
+	"github.com/deis/controller-sdk-go/domains"
+	"github.com/deis/controller-sdk-go/rollout"
+)
+
+// listLimit is the number of domains and certificates requested when listing them.
+const listLimit = 1000
+
+var (
+	// ErrNoDomains is returned when no production domains are given.
+	ErrNoDomains = errors.New("No production domains were given")
+	// ErrNoLiveApp is returned when neither app of the pair serves the production domains.
+	ErrNoLiveApp = errors.New("Neither app serves the production domains")
+	// ErrSplitDomains is returned when the production domains are split between the apps.
+	ErrSplitDomains = errors.New("The production domains are split between both apps")
+)
+
+// ErrSwitchFailed is returned when moving a domain fails. The domains moved before the
+// failure are moved back. If any of them couldn't be, RestoreErr is an ErrRestoreFailed.
+type ErrSwitchFailed struct {
+	Domain     string
+	Err        error
+	RestoreErr error
+}
+
+func (e ErrSwitchFailed) Error() string {
+	if e.RestoreErr != nil {
+		return fmt.Sprintf("Moving %s failed: %v. Restoring the domains also failed: %v",
+			e.Domain, e.Err, e.RestoreErr)
+	}
+	return fmt.Sprintf("Moving %s failed: %v. The domains were restored", e.Domain, e.Err)
+}
+
+// ErrRestoreFailed is returned when restoring domains after a failed switch fails. Every step
+// is still attempted, so Errs has one error for each call that failed.
+type ErrRestoreFailed struct {
+	Errs []error
+}
+
+func (e ErrRestoreFailed) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Pair is the two apps of a blue/green deploy.
+type Pair struct {
+	Blue  string
+	Green string
+}
+
+// Options controls a blue/green deploy.
+type Options struct {
+	// Domains are the production domains moved between the apps.
+	Domains []string
+	// Wait controls how the idle app's release is waited on.
+	Wait rollout.Options
+}
+
+// Result describes a blue/green deploy.
+type Result struct {
+	// Live is the app now serving the domains.
+	Live string
+	// Previous is the app that served the domains before, which is kept running.
+	Previous string
+	// Version is the live app's release.
+	Version int
+}
+
+// Live returns which app of the pair serves all of the domains and which is idle.
+func Live(c *deis.Client, pair Pair, ds []string) (string, string, error) {
+	if len(ds) == 0 {
+		return "", "", ErrNoDomains
+	}
+
+	blue, err := hosted(c, pair.Blue, ds)
+	if err != nil {
+		return "", "", err
+	}
+	green, err := hosted(c, pair.Green, ds)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case blue == len(ds) && green == 0:
+		return pair.Blue, pair.Green, nil
+	case green == len(ds) && blue == 0:
+		return pair.Green, pair.Blue, nil
+	case blue == 0 && green == 0:
+		return "", "", ErrNoLiveApp
+	default:
+		return "", "", ErrSplitDomains
+	}
+}
+
+// hosted counts how many of the domains belong to an app.
+func hosted(c *deis.Client, appID string, ds []string) (int, error) {
+	appDomains, _, err := domains.List(c, appID, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return 0, err
+	}
+
+	found := map[string]bool{}
+	for _, d := range appDomains {
+		found[d.Domain] = true
+	}
+
+	count := 0
+	for _, d := range ds {
+		if found[d] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Deploy deploys a build to the idle app of the pair, waits for its pods to be up and moves
+// the domains to it. The domains aren't moved until the idle app has pods of the new release,
+// as rollout.Progress.Done requires.
+func Deploy(ctx context.Context, c *deis.Client, pair Pair, image string, procfile map[string]string, opts Options) (Result, error) {
+	live, idle, err := Live(c, pair, opts.Domains)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if _, err := builds.New(c, idle, image, procfile); err != nil && !deis.IsErrAPIMismatch(err) {
+		return Result{}, err
+	}
+
+	progress, err := rollout.Wait(ctx, c, idle, -1, opts.Wait)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := Switch(c, live, idle, opts.Domains); err != nil {
+		return Result{}, err
+	}
+
+	return Result{Live: idle, Previous: live, Version: progress.Version}, nil
+}
+
+// step is a completed part of moving a domain.
+type step struct {
+	domain string
+	// removed is true once the domain is removed from the old app.
+	removed bool
+	// added is true once the domain is added to the new app.
+	added bool
+	// cert is the certificate the domain was attached to, if any.
+	cert string
+}
+
+// Switch moves domains from one app to another, re-attaching their certificates.
+// If a domain fails to move, the domains are restored and an ErrSwitchFailed is returned.
+func Switch(c *deis.Client, from, to string, ds []string) error {
+	if len(ds) == 0 {
+		return ErrNoDomains
+	}
+
+	attached, err := certificates(c)
+	if err != nil {
+		return err
+	}
+
+	var steps []*step
+	for _, d := range ds {
+		s := &step{domain: d, cert: attached[d]}
+		steps = append(steps, s)
+
+		if err := move(c, from, to, s); err != nil {
+			return ErrSwitchFailed{Domain: d, Err: err, RestoreErr: restore(c, from, to, steps)}
+		}
+	}
+
+	return nil
+}
+
+func move(c *deis.Client, from, to string, s *step) error {
+	if err := domains.Delete(c, from, s.domain); err != nil && !deis.IsErrAPIMismatch(err) {
+		return err
+	}
+	s.removed = true
+
+	if _, err := domains.New(c, to, s.domain); err != nil && !deis.IsErrAPIMismatch(err) {
+		return err
+	}
+	s.added = true
+
+	if s.cert != "" {
+		if err := certs.Attach(c, s.cert, s.domain); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore undoes the steps in reverse. It keeps going when a call fails, so every domain that
+// can be restored is, and returns the failures together as an ErrRestoreFailed.
+func restore(c *deis.Client, from, to string, steps []*step) error {
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		if s.added {
+			if err := domains.Delete(c, to, s.domain); err != nil && !deis.IsErrAPIMismatch(err) {
+				errs = append(errs, fmt.Errorf("removing %s from %s: %v", s.domain, to, err))
+			}
+		}
+		if s.removed {
+			if _, err := domains.New(c, from, s.domain); err != nil && !deis.IsErrAPIMismatch(err) {
+				errs = append(errs, fmt.Errorf("adding %s to %s: %v", s.domain, from, err))
+				continue
+			}
+			if s.cert != "" {
+				if err := certs.Attach(c, s.cert, s.domain); err != nil && !deis.IsErrAPIMismatch(err) {
+					errs = append(errs, fmt.Errorf("attaching %s to %s: %v", s.cert, s.domain, err))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return ErrRestoreFailed{Errs: errs}
+	}
+	return nil
+}
+
+func certificates(c *deis.Client) (map[string]string, error) {
+	cs, _, err := certs.List(c, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+
+	attached := map[string]string{}
+	for _, cert := range cs {
+		for _, d := range cert.Domains {
+			attached[d] = cert.Name
+		}
+	}
+	return attached, nil
+}
@@ -0,0 +1,301 @@
+package bluegreen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/rollout"
+)
+
+// fakeHTTPServer keeps the domains of each app and the domains attached to certificates.
+// Adding an app's failing domain to it fails.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	domains  map[string][]string
+	certs    map[string][]string
+	releases map[string]int
+	failing  map[string]string
+	requests []string
+}
+
+func newFakeHTTPServer() *fakeHTTPServer {
+	return &fakeHTTPServer{
+		domains: map[string][]string{
+			"example-blue":  {"example-blue", "example.com", "www.example.com"},
+			"example-green": {"example-green"},
+		},
+		certs:    map[string][]string{"example-cert": {"example.com"}},
+		releases: map[string]int{"example-blue": 4, "example-green": 3},
+		failing:  map[string]string{},
+	}
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+	if req.Method != "GET" {
+		f.requests = append(f.requests, strings.TrimSpace(fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body)))
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	if parts[1] == "certs" {
+		if req.Method == "GET" {
+			var results []string
+			for name, ds := range f.certs {
+				out, _ := json.Marshal(ds)
+				results = append(results, fmt.Sprintf(`{"name": "%s", "domains": %s}`, name, out))
+			}
+			res.Write([]byte(fmt.Sprintf(`{"count": %d, "results": [%s]}`, len(results), strings.Join(results, ","))))
+			return
+		}
+		attach := struct{ Domain string }{}
+		json.Unmarshal(body, &attach)
+		f.certs[parts[2]] = append(f.certs[parts[2]], attach.Domain)
+		res.WriteHeader(http.StatusCreated)
+		res.Write(nil)
+		return
+	}
+
+	app := parts[2]
+	switch {
+	case parts[3] == "domains" && req.Method == "GET":
+		var results []string
+		for _, d := range f.domains[app] {
+			results = append(results, fmt.Sprintf(`{"app": "%s", "domain": "%s"}`, app, d))
+		}
+		res.Write([]byte(fmt.Sprintf(`{"count": %d, "results": [%s]}`, len(results), strings.Join(results, ","))))
+	case parts[3] == "domains" && req.Method == "POST":
+		create := struct{ Domain string }{}
+		json.Unmarshal(body, &create)
+		if create.Domain == f.failing[app] {
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		f.domains[app] = append(f.domains[app], create.Domain)
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(fmt.Sprintf(`{"app": "%s", "domain": "%s"}`, app, create.Domain)))
+	case parts[3] == "domains" && req.Method == "DELETE":
+		f.domains[app] = without(f.domains[app], parts[4])
+		// Removing a domain from an app detaches its certificate.
+		for name, ds := range f.certs {
+			f.certs[name] = without(ds, parts[4])
+		}
+		res.WriteHeader(http.StatusNoContent)
+		res.Write(nil)
+	case parts[3] == "builds" && req.Method == "POST":
+		f.releases[app]++
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(`{}`))
+	case parts[3] == "releases" && req.Method == "GET":
+		res.Write([]byte(fmt.Sprintf(`{"count": 1, "results": [{"version": %d}]}`, f.releases[app])))
+	case parts[3] == "pods" && req.Method == "GET":
+		res.Write([]byte(fmt.Sprintf(`{"count": 1, "results": [{"release": "v%d", "type": "web", "name": "web-1", "state": "up", "started": "2016-02-13T00:47:52"}]}`, f.releases[app])))
+	default:
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+	}
+}
+
+func without(list []string, item string) []string {
+	var out []string
+	for _, s := range list {
+		if s != item {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+var pair = Pair{Blue: "example-blue", Green: "example-green"}
+var production = []string{"example.com", "www.example.com"}
+
+func TestDeploy(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Deploy(context.Background(), deis, pair, "deis/example-go:v2", nil, Options{
+		Domains: production,
+		Wait:    rollout.Options{Interval: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Result{Live: "example-green", Previous: "example-blue", Version: 4}
+	if result != expected {
+		t.Errorf("Expected %v, Got %v", expected, result)
+	}
+
+	expectedDomains := map[string][]string{
+		"example-blue":  {"example-blue"},
+		"example-green": {"example-green", "example.com", "www.example.com"},
+	}
+	if !reflect.DeepEqual(expectedDomains, handler.domains) {
+		t.Errorf("Expected %v, Got %v", expectedDomains, handler.domains)
+	}
+
+	if !reflect.DeepEqual([]string{"example.com"}, handler.certs["example-cert"]) {
+		t.Errorf("Expected the certificate to be re-attached, Got %v", handler.certs)
+	}
+
+	live, idle, err := Live(deis, pair, production)
+	if err != nil || live != "example-green" || idle != "example-blue" {
+		t.Errorf("Expected example-green to be live, Got %s, %s, %v", live, idle, err)
+	}
+}
+
+func TestSwitchRestores(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	handler.failing["example-green"] = "www.example.com"
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Switch(deis, "example-blue", "example-green", production)
+	failure, ok := err.(ErrSwitchFailed)
+	if !ok {
+		t.Fatalf("Expected ErrSwitchFailed, Got %v", err)
+	}
+	if failure.Domain != "www.example.com" || failure.RestoreErr != nil {
+		t.Errorf("Expected www.example.com to fail and be restored, Got %v", failure)
+	}
+
+	for app, ds := range handler.domains {
+		sort.Strings(ds)
+		handler.domains[app] = ds
+	}
+	expectedDomains := map[string][]string{
+		"example-blue":  {"example-blue", "example.com", "www.example.com"},
+		"example-green": {"example-green"},
+	}
+	if !reflect.DeepEqual(expectedDomains, handler.domains) {
+		t.Errorf("Expected %v, Got %v", expectedDomains, handler.domains)
+	}
+
+	if !reflect.DeepEqual([]string{"example.com"}, handler.certs["example-cert"]) {
+		t.Errorf("Expected the certificate to be re-attached, Got %v", handler.certs)
+	}
+
+	expected := []string{
+		"DELETE /v2/apps/example-blue/domains/example.com",
+		`POST /v2/apps/example-green/domains/ {"domain":"example.com"}`,
+		`POST /v2/certs/example-cert/domain/ {"domain":"example.com"}`,
+		"DELETE /v2/apps/example-blue/domains/www.example.com",
+		`POST /v2/apps/example-green/domains/ {"domain":"www.example.com"}`,
+		`POST /v2/apps/example-blue/domains/ {"domain":"www.example.com"}`,
+		"DELETE /v2/apps/example-green/domains/example.com",
+		`POST /v2/apps/example-blue/domains/ {"domain":"example.com"}`,
+		`POST /v2/certs/example-cert/domain/ {"domain":"example.com"}`,
+	}
+	if !reflect.DeepEqual(expected, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expected, handler.requests)
+	}
+}
+
+func TestSwitchRestoresEveryDomain(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	handler.domains["example-blue"] = append(handler.domains["example-blue"], "api.example.com")
+	handler.failing["example-green"] = "api.example.com"
+	handler.failing["example-blue"] = "www.example.com"
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Switch(deis, "example-blue", "example-green", append(production, "api.example.com"))
+	failure, ok := err.(ErrSwitchFailed)
+	if !ok {
+		t.Fatalf("Expected ErrSwitchFailed, Got %v", err)
+	}
+	restoreErr, ok := failure.RestoreErr.(ErrRestoreFailed)
+	if !ok || len(restoreErr.Errs) != 1 {
+		t.Fatalf("Expected one restore error, Got %v", failure.RestoreErr)
+	}
+
+	// www.example.com couldn't be added back, but example.com still was after it.
+	for app, ds := range handler.domains {
+		sort.Strings(ds)
+		handler.domains[app] = ds
+	}
+	expectedDomains := map[string][]string{
+		"example-blue":  {"api.example.com", "example-blue", "example.com"},
+		"example-green": {"example-green"},
+	}
+	if !reflect.DeepEqual(expectedDomains, handler.domains) {
+		t.Errorf("Expected %v, Got %v", expectedDomains, handler.domains)
+	}
+
+	if !reflect.DeepEqual([]string{"example.com"}, handler.certs["example-cert"]) {
+		t.Errorf("Expected the certificate to be re-attached, Got %v", handler.certs)
+	}
+}
+
+func TestLive(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Live(deis, pair, nil); err != ErrNoDomains {
+		t.Errorf("Expected %v, Got %v", ErrNoDomains, err)
+	}
+
+	if _, _, err := Live(deis, pair, []string{"other.com"}); err != ErrNoLiveApp {
+		t.Errorf("Expected %v, Got %v", ErrNoLiveApp, err)
+	}
+
+	handler.domains["example-green"] = append(handler.domains["example-green"], "www.example.com")
+	handler.domains["example-blue"] = without(handler.domains["example-blue"], "www.example.com")
+	if _, _, err := Live(deis, pair, production); err != ErrSplitDomains {
+		t.Errorf("Expected %v, Got %v", ErrSplitDomains, err)
+	}
+}
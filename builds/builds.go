@@ -7,6 +7,8 @@ import (
 
 	deis "github.com/deis/controller-sdk-go"
 	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/imageref"
+	"github.com/deis/controller-sdk-go/procfile"
 )
 
 // List lists an app's builds.
@@ -36,29 +38,33 @@ func List(c *deis.Client, appID string, results int) ([]api.Build, int, error) {
 // This example adds custom registry credentials to an app:
 //    import (
 //    	"github.com/deis/controller-sdk-go/api"
-//    	"github.com/deis/controller-sdk-go/config"
+//    	"github.com/deis/controller-sdk-go/registry"
 //    )
 //
-//    // Create username/password map
-//    registryMap := map[string]string{
-//    	"username": "password"
-//    }
-//
-//    // Create a new configuration, assign the credentials, and set it.
-//    // Note that config setting is a patching operation, it doesn't overwrite or unset
+//    // Note that setting credentials is a patching operation, it doesn't overwrite or unset
 //    // unrelated configuration.
-//    newConfig := api.Config{}
-//    newConfig.Registry = registryMap
-//    _, err := config.Set(<client>, "appname", newConfig)
+//    creds := api.RegistryCredentials{Username: "username", Password: "password"}
+//    _, err := registry.Set(<client>, "appname", creds)
 //    if err != nil {
 //        log.Fatal(err)
 //    }
+//
+// The image and procfile are validated before they are sent. An imageref.ErrInvalidReference
+// is returned if the image doesn't follow the Docker reference grammar, and a procfile error if
+// a process type breaks the controller's naming rules. See procfile.Validate.
 func New(c *deis.Client, appID string, image string,
-	procfile map[string]string) (api.Build, error) {
+	processes map[string]string) (api.Build, error) {
+
+	if err := imageref.Validate(image); err != nil {
+		return api.Build{}, err
+	}
+	if err := procfile.Validate(processes); err != nil {
+		return api.Build{}, err
+	}
 
 	u := fmt.Sprintf("/v2/apps/%s/builds/", appID)
 
-	req := api.CreateBuildRequest{Image: image, Procfile: procfile}
+	req := api.CreateBuildRequest{Image: image, Procfile: processes}
 
 	body, err := json.Marshal(req)
 
@@ -10,6 +10,8 @@ import (
 
 	deis "github.com/deis/controller-sdk-go"
 	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/imageref"
+	"github.com/deis/controller-sdk-go/procfile"
 )
 
 const buildsFixture string = `
@@ -164,3 +166,40 @@ func TestBuildCreate(t *testing.T) {
 		t.Error(fmt.Errorf("Expected %v, Got %v", expected, actual))
 	}
 }
+
+func TestBuildCreateInvalidImage(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(deis, "example-go", "deis/Example-go:latest", nil)
+	expected := imageref.ErrInvalidReference{Reference: "deis/Example-go:latest", Reason: "the repository must be lowercase"}
+	if err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+}
+
+func TestBuildCreateInvalidProcfile(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(deis, "example-go", "deis/example-go", map[string]string{"Web": "example-go"})
+	if expected := (procfile.ErrInvalidType{Type: "Web"}); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+}
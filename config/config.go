@@ -38,8 +38,14 @@ func List(c *deis.Client, app string) (api.Config, error) {
 // Calling Set() with an empty api.Config will return a deis.ErrConflict.
 // Trying to unset a key that does not exist returns a deis.ErrUnprocessable.
 // Trying to set a tag that is not a label in the kubernetes cluster will return a deis.ErrTagNotFound.
+// Use SetIfUnmodified or Update to avoid overwriting concurrent changes.
 func Set(c *deis.Client, app string, config api.Config) (api.Config, error) {
-	body, err := json.Marshal(config)
+	return post(c, app, config)
+}
+
+// post sends a config patch, such as an api.Config or api.ConfigSet, to an app.
+func post(c *deis.Client, app string, patch interface{}) (api.Config, error) {
+	body, err := json.Marshal(patch)
 
 	if err != nil {
 		return api.Config{}, err
@@ -60,3 +66,97 @@ func Set(c *deis.Client, app string, config api.Config) (api.Config, error) {
 
 	return newConfig, reqErr
 }
+
+// SetValues sets an app's environment variables and creates a new release.
+// The keys and values are checked with Validate before contacting the controller.
+func SetValues(c *deis.Client, app string, values map[string]string) (api.Config, error) {
+	if len(values) == 0 {
+		return api.Config{}, ErrNoChanges
+	}
+	if err := Validate(api.Config{Values: interfaces(values)}); err != nil {
+		return api.Config{}, err
+	}
+	return post(c, app, api.ConfigSet{Values: values})
+}
+
+// UnsetValues unsets an app's environment variables and creates a new release.
+func UnsetValues(c *deis.Client, app string, keys ...string) (api.Config, error) {
+	if len(keys) == 0 {
+		return api.Config{}, ErrNoChanges
+	}
+	values := nils(keys)
+	if err := Validate(api.Config{Values: values}); err != nil {
+		return api.Config{}, err
+	}
+	return post(c, app, api.ConfigUnset{Values: values})
+}
+
+// SetMemory sets the memory limits of an app's process types, such as "web": "512M", and
+// creates a new release. A limit can be a request and limit pair, such as "256M/512M".
+func SetMemory(c *deis.Client, app string, limits map[string]string) (api.Config, error) {
+	return patch(c, app, api.Config{Memory: interfaces(limits)})
+}
+
+// UnsetMemory removes the memory limits of an app's process types and creates a new release.
+func UnsetMemory(c *deis.Client, app string, procTypes ...string) (api.Config, error) {
+	return patch(c, app, api.Config{Memory: nils(procTypes)})
+}
+
+// SetCPU sets the CPU limits of an app's process types, such as "web": "500m", and creates a
+// new release. A limit can be a request and limit pair, such as "250m/1".
+func SetCPU(c *deis.Client, app string, limits map[string]string) (api.Config, error) {
+	return patch(c, app, api.Config{CPU: interfaces(limits)})
+}
+
+// UnsetCPU removes the CPU limits of an app's process types and creates a new release.
+func UnsetCPU(c *deis.Client, app string, procTypes ...string) (api.Config, error) {
+	return patch(c, app, api.Config{CPU: nils(procTypes)})
+}
+
+// SetTags restricts an app to nodes with the given labels and creates a new release.
+func SetTags(c *deis.Client, app string, tags map[string]string) (api.Config, error) {
+	return patch(c, app, api.Config{Tags: interfaces(tags)})
+}
+
+// UnsetTags removes node label restrictions from an app and creates a new release.
+func UnsetTags(c *deis.Client, app string, keys ...string) (api.Config, error) {
+	return patch(c, app, api.Config{Tags: nils(keys)})
+}
+
+// SetRegistry sets an app's private registry settings, such as "username" and "password",
+// and creates a new release. See the registry package for typed credentials.
+func SetRegistry(c *deis.Client, app string, registry map[string]string) (api.Config, error) {
+	return patch(c, app, api.Config{Registry: interfaces(registry)})
+}
+
+// UnsetRegistry removes an app's private registry settings and creates a new release.
+func UnsetRegistry(c *deis.Client, app string, keys ...string) (api.Config, error) {
+	return patch(c, app, api.Config{Registry: nils(keys)})
+}
+
+// patch validates a config with a single non-empty section and sets it.
+func patch(c *deis.Client, app string, config api.Config) (api.Config, error) {
+	if len(config.Memory)+len(config.CPU)+len(config.Tags)+len(config.Registry) == 0 {
+		return api.Config{}, ErrNoChanges
+	}
+	if err := Validate(config); err != nil {
+		return api.Config{}, err
+	}
+	return post(c, app, config)
+}
+
+func interfaces(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		out[key] = value
+	}
+	return out
+}
+
+func nils(keys []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		out[key] = nil
+	}
+	return out
+}
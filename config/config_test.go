@@ -6,10 +6,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
 	deis "github.com/deis/controller-sdk-go"
 	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/procfile"
 )
 
 const configFixture string = `
@@ -280,3 +283,258 @@ func TestConfigList(t *testing.T) {
 		t.Errorf("Expected %v, Got %v", expected, actual)
 	}
 }
+
+// recordingServer records the body of every config change.
+type recordingServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (r *recordingServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+	r.requests = append(r.requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body))
+
+	res.WriteHeader(http.StatusCreated)
+	res.Write([]byte(configFixture))
+}
+
+func TestTypedSetters(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []func() (api.Config, error){
+		func() (api.Config, error) { return SetValues(deis, "example-go", map[string]string{"FOO": "bar"}) },
+		func() (api.Config, error) { return UnsetValues(deis, "example-go", "FOO", "_BAR") },
+		func() (api.Config, error) { return SetMemory(deis, "example-go", map[string]string{"web": "256M/1G"}) },
+		func() (api.Config, error) { return UnsetMemory(deis, "example-go", "web") },
+		func() (api.Config, error) { return SetCPU(deis, "example-go", map[string]string{"web": "500m"}) },
+		func() (api.Config, error) { return UnsetCPU(deis, "example-go", "web") },
+		func() (api.Config, error) { return SetTags(deis, "example-go", map[string]string{"disk": "ssd"}) },
+		func() (api.Config, error) { return UnsetTags(deis, "example-go", "disk") },
+		func() (api.Config, error) {
+			return SetRegistry(deis, "example-go", map[string]string{"username": "bob"})
+		},
+		func() (api.Config, error) { return UnsetRegistry(deis, "example-go", "username") },
+	}
+	for _, call := range calls {
+		if _, err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		`POST /v2/apps/example-go/config/ {"values":{"FOO":"bar"}}`,
+		`POST /v2/apps/example-go/config/ {"values":{"FOO":null,"_BAR":null}}`,
+		`POST /v2/apps/example-go/config/ {"memory":{"web":"256M/1G"}}`,
+		`POST /v2/apps/example-go/config/ {"memory":{"web":null}}`,
+		`POST /v2/apps/example-go/config/ {"cpu":{"web":"500m"}}`,
+		`POST /v2/apps/example-go/config/ {"cpu":{"web":null}}`,
+		`POST /v2/apps/example-go/config/ {"tags":{"disk":"ssd"}}`,
+		`POST /v2/apps/example-go/config/ {"tags":{"disk":null}}`,
+		`POST /v2/apps/example-go/config/ {"registry":{"username":"bob"}}`,
+		`POST /v2/apps/example-go/config/ {"registry":{"username":null}}`,
+	}
+	if !reflect.DeepEqual(expected, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expected, handler.requests)
+	}
+}
+
+func TestTypedSettersValidate(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		err      error
+		expected error
+	}{
+		{second(SetValues(deis, "example-go", nil)), ErrNoChanges},
+		{second(SetValues(deis, "example-go", map[string]string{"1FOO": "bar"})), ErrInvalidKey{Key: "1FOO"}},
+		{second(SetValues(deis, "example-go", map[string]string{"FOO-BAR": "bar"})), ErrInvalidKey{Key: "FOO-BAR"}},
+		{second(SetValues(deis, "example-go", map[string]string{"DEIS_APP": "x"})), ErrReservedKey{Key: "DEIS_APP"}},
+		{second(UnsetValues(deis, "example-go", "deis_debug")), ErrReservedKey{Key: "deis_debug"}},
+		{second(SetValues(deis, "example-go", map[string]string{"BIG": strings.Repeat("a", MaxValueSize+1)})),
+			ErrValueTooLarge{Key: "BIG", Size: MaxValueSize + 1}},
+		{second(SetMemory(deis, "example-go", map[string]string{"web": "1T"})),
+			ErrInvalidLimit{Resource: "memory", Type: "web", Value: "1T"}},
+		{second(SetCPU(deis, "example-go", map[string]string{"web": "half"})),
+			ErrInvalidLimit{Resource: "cpu", Type: "web", Value: "half"}},
+		{second(UnsetCPU(deis, "example-go", "Web")), procfile.ErrInvalidType{Type: "Web"}},
+		{second(SetTags(deis, "example-go", map[string]string{"disk": "fast ssd"})), ErrInvalidTag{Key: "disk", Value: "fast ssd"}},
+		{second(UnsetTags(deis, "example-go")), ErrNoChanges},
+	}
+	for _, check := range checks {
+		if check.err != check.expected {
+			t.Errorf("Expected %v, Got %v", check.expected, check.err)
+		}
+	}
+
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no requests, Got %v", handler.requests)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := api.Config{
+		Values: map[string]interface{}{"FOO": "bar", "_x1": 4, "OLD": nil},
+		Memory: map[string]interface{}{"web": "512MB", "worker": "0", "cmd": "1g/2G"},
+		CPU:    map[string]interface{}{"web": "1", "worker": "250m/1.5"},
+		Tags:   map[string]interface{}{"kubernetes.io/role": "node", "disk": "ssd", "old": nil},
+	}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Expected no error, Got %v", err)
+	}
+}
+
+func second(_ api.Config, err error) error {
+	return err
+}
+
+// versionedServer serves a config whose UUID changes on every change. Another client changes
+// the config just before the reads numbered in concurrent.
+type versionedServer struct {
+	mu         sync.Mutex
+	version    int
+	reads      int
+	concurrent map[int]bool
+	requests   []string
+}
+
+func (v *versionedServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if req.Method == "GET" {
+		v.reads++
+		if v.concurrent[v.reads] {
+			v.version++
+		}
+	} else {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fmt.Println(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		v.requests = append(v.requests, string(body))
+		v.version++
+		res.WriteHeader(http.StatusCreated)
+	}
+
+	res.Write([]byte(fmt.Sprintf(`{"app": "example-go", "values": {"N": "%d"}, "updated": "2014-01-0%dT00:00:00UTC", "uuid": "uuid-%d"}`,
+		v.version, v.version+1, v.version)))
+}
+
+func TestSetIfUnmodified(t *testing.T) {
+	t.Parallel()
+
+	handler := &versionedServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := api.Config{Values: map[string]interface{}{"FOO": "bar"}}
+
+	if _, err := SetIfUnmodified(deis, "example-go", "uuid-0", patch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetIfUnmodified(deis, "example-go", "2014-01-02T00:00:00UTC", patch); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ErrModified{App: "example-go", Expected: "uuid-0", Actual: "uuid-2"}
+	if _, err := SetIfUnmodified(deis, "example-go", "uuid-0", patch); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+	if _, err := SetIfUnmodified(deis, "example-go", "", patch); err != ErrNoExpected {
+		t.Errorf("Expected %v, Got %v", ErrNoExpected, err)
+	}
+	if len(handler.requests) != 2 {
+		t.Errorf("Expected 2 changes, Got %v", handler.requests)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	handler := &versionedServer{concurrent: map[int]bool{2: true}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []interface{}
+	merge := func(current api.Config) (api.Config, error) {
+		seen = append(seen, current.Values["N"])
+		return api.Config{Values: map[string]interface{}{"M": current.Values["N"]}}, nil
+	}
+
+	cfg, err := Update(deis, "example-go", 1, merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UUID != "uuid-2" {
+		t.Errorf("Expected uuid-2, Got %s", cfg.UUID)
+	}
+
+	expected := []interface{}{"0", "1"}
+	if !reflect.DeepEqual(expected, seen) {
+		t.Errorf("Expected %v, Got %v", expected, seen)
+	}
+	requests := []string{`{"values":{"M":"1"}}`}
+	if !reflect.DeepEqual(requests, handler.requests) {
+		t.Errorf("Expected %v, Got %v", requests, handler.requests)
+	}
+
+	handler.concurrent = map[int]bool{6: true}
+	_, err = Update(deis, "example-go", 0, merge)
+	if _, ok := err.(ErrModified); !ok {
+		t.Errorf("Expected ErrModified, Got %v", err)
+	}
+
+	reads := handler.reads
+	if _, err = Update(deis, "example-go", -1, merge); err != ErrNegativeRetries {
+		t.Errorf("Expected %v, Got %v", ErrNegativeRetries, err)
+	}
+	if handler.reads != reads {
+		t.Errorf("Expected no reads with negative retries, Got %d", handler.reads-reads)
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+var (
+	// ErrNoExpected is returned by SetIfUnmodified when no UUID or Updated time is expected.
+	ErrNoExpected = errors.New("No expected UUID or Updated time was given")
+	// ErrNegativeRetries is returned by Update when retries is less than zero.
+	ErrNegativeRetries = errors.New("The number of retries can't be negative")
+)
+
+// ErrModified is returned when an app's config changed after it was read.
+type ErrModified struct {
+	App string
+	// Expected is the UUID or Updated time the config was expected to have.
+	Expected string
+	// Actual is the UUID of the config when it was read again.
+	Actual string
+}
+
+func (e ErrModified) Error() string {
+	return fmt.Sprintf("The config of %s was modified after it was read: expected %s, found %s", e.App, e.Expected, e.Actual)
+}
+
+// SetIfUnmodified sets an app's config like Set, unless it was modified since a List returned
+// it. Expected is the UUID or Updated time of the config that List returned. The config is read
+// again before it's set, and an ErrModified is returned if it changed. An empty expected
+// would match a config without a UUID or Updated time, so it returns ErrNoExpected.
+//
+// Only the keys in config are sent, so a concurrent change to other keys is kept either way;
+// the check guards keys that were computed from their old values. It's made by the client in
+// two requests, so a change landing between them still goes unnoticed.
+func SetIfUnmodified(c *deis.Client, app string, expected string, config api.Config) (api.Config, error) {
+	if expected == "" {
+		return api.Config{}, ErrNoExpected
+	}
+
+	current, err := List(c, app)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.Config{}, err
+	}
+	if expected != current.UUID && expected != current.Updated {
+		return api.Config{}, ErrModified{App: app, Expected: expected, Actual: current.UUID}
+	}
+	return Set(c, app, config)
+}
+
+// MergeFunc returns the patch to set on an app's config, given its current config.
+type MergeFunc func(current api.Config) (api.Config, error)
+
+// Update reads an app's config, calls merge with it, and sets the patch merge returns with
+// SetIfUnmodified. When the config is modified in between, it's read again and merge is called
+// again, up to retries times, after which the ErrModified is returned. A negative retries
+// returns ErrNegativeRetries.
+//
+// This example adds a domain to a comma separated list without losing concurrent additions:
+//
+//    _, err := config.Update(client, "example-go", 3, func(current api.Config) (api.Config, error) {
+//        hosts, _ := current.Values["ALLOWED_HOSTS"].(string)
+//        if hosts != "" {
+//            hosts += ","
+//        }
+//        return api.Config{Values: map[string]interface{}{
+//            "ALLOWED_HOSTS": hosts + "example.com",
+//        }}, nil
+//    })
+func Update(c *deis.Client, app string, retries int, merge MergeFunc) (api.Config, error) {
+	if retries < 0 {
+		return api.Config{}, ErrNegativeRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		current, err := List(c, app)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return api.Config{}, err
+		}
+
+		expected := current.UUID
+		if expected == "" {
+			expected = current.Updated
+		}
+
+		patch, err := merge(current)
+		if err != nil {
+			return api.Config{}, err
+		}
+
+		config, err := SetIfUnmodified(c, app, expected, patch)
+		if _, ok := err.(ErrModified); ok && attempt < retries {
+			continue
+		}
+		return config, err
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/procfile"
+)
+
+const (
+	// MaxValueSize is the largest config value in bytes. An app's config is stored in a single
+	// Kubernetes secret, which can't be larger than 1 MiB.
+	MaxValueSize = 1024 * 1024
+	// reservedPrefix starts the names of variables set by the platform.
+	reservedPrefix = "DEIS_"
+)
+
+var (
+	keyPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	memoryPattern = regexp.MustCompile(`(?i)^(?:[0-9]+(?:MB|KB|GB|[BKMG])|0)(?:/[0-9]+(?:MB|KB|GB|[BKMG]))?$`)
+	cpuPattern    = regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+m?(?:/[-+]?[0-9]*\.?[0-9]+m?)?$`)
+	labelName     = regexp.MustCompile(`^[A-Za-z0-9](?:[-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelPrefix   = regexp.MustCompile(`^[a-z0-9](?:[-a-z0-9]*[a-z0-9])?(?:\.[a-z0-9](?:[-a-z0-9]*[a-z0-9])?)*$`)
+	labelValue    = regexp.MustCompile(`^(?:[A-Za-z0-9](?:[-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+)
+
+// ErrNoChanges is returned when a config operation is given nothing to change.
+var ErrNoChanges = errors.New("No config changes were given")
+
+// ErrInvalidKey is returned when a config key isn't a valid POSIX environment variable name.
+type ErrInvalidKey struct {
+	Key string
+}
+
+func (e ErrInvalidKey) Error() string {
+	return fmt.Sprintf("Invalid config key %q: keys must start with a letter or underscore and "+
+		"contain only letters, numbers and underscores", e.Key)
+}
+
+// ErrReservedKey is returned when a config key is reserved for the platform.
+type ErrReservedKey struct {
+	Key string
+}
+
+func (e ErrReservedKey) Error() string {
+	return fmt.Sprintf("The config key %s is reserved: keys starting with %s are set by the platform",
+		e.Key, reservedPrefix)
+}
+
+// ErrValueTooLarge is returned when a config value is larger than MaxValueSize.
+type ErrValueTooLarge struct {
+	Key  string
+	Size int
+}
+
+func (e ErrValueTooLarge) Error() string {
+	return fmt.Sprintf("The value of %s is %d bytes, larger than the limit of %d bytes",
+		e.Key, e.Size, MaxValueSize)
+}
+
+// ErrInvalidLimit is returned when a memory or CPU limit isn't in the controller's format.
+type ErrInvalidLimit struct {
+	// Resource is "memory" or "cpu".
+	Resource string
+	Type     string
+	Value    string
+}
+
+func (e ErrInvalidLimit) Error() string {
+	return fmt.Sprintf("Invalid %s limit %q for %s", e.Resource, e.Value, e.Type)
+}
+
+// ErrInvalidTag is returned when a tag isn't a valid Kubernetes node label.
+type ErrInvalidTag struct {
+	Key   string
+	Value string
+}
+
+func (e ErrInvalidTag) Error() string {
+	return fmt.Sprintf("Invalid tag %s=%s: tags must be valid Kubernetes labels", e.Key, e.Value)
+}
+
+// ValidateKey returns an error if a config key isn't a valid environment variable name or is
+// reserved.
+func ValidateKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return ErrInvalidKey{Key: key}
+	}
+	if strings.HasPrefix(strings.ToUpper(key), reservedPrefix) {
+		return ErrReservedKey{Key: key}
+	}
+	return nil
+}
+
+// ValidMemoryLimit returns true if a memory limit is in the controller's format, a whole number
+// followed by B, K, M or G with an optional B, in any case, such as "512M", or a request and a
+// limit, such as "256M/512M". The limits package parses limits in this format.
+func ValidMemoryLimit(value string) bool {
+	return memoryPattern.MatchString(value)
+}
+
+// Validate checks an api.Config before it is set, in the order values, memory, CPU and tags,
+// each sorted by key. Nil entries, which unset a key, only have their keys checked.
+func Validate(config api.Config) error {
+	for _, key := range sortedKeys(config.Values) {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+		if value := config.Values[key]; value != nil {
+			if size := len(fmt.Sprint(value)); size > MaxValueSize {
+				return ErrValueTooLarge{Key: key, Size: size}
+			}
+		}
+	}
+
+	limits := []struct {
+		resource string
+		values   map[string]interface{}
+		pattern  *regexp.Regexp
+	}{
+		{"memory", config.Memory, memoryPattern},
+		{"cpu", config.CPU, cpuPattern},
+	}
+	for _, limit := range limits {
+		for _, procType := range sortedKeys(limit.values) {
+			if err := procfile.ValidateType(procType); err != nil {
+				return err
+			}
+			if value := limit.values[procType]; value != nil && !limit.pattern.MatchString(fmt.Sprint(value)) {
+				return ErrInvalidLimit{Resource: limit.resource, Type: procType, Value: fmt.Sprint(value)}
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(config.Tags) {
+		value := ""
+		if config.Tags[key] != nil {
+			value = fmt.Sprint(config.Tags[key])
+		}
+		if !validLabelKey(key) || !labelValue.MatchString(value) {
+			return ErrInvalidTag{Key: key, Value: value}
+		}
+	}
+
+	return nil
+}
+
+// validLabelKey checks a Kubernetes label key, an optional DNS subdomain prefix and a name.
+func validLabelKey(key string) bool {
+	name := key
+	if i := strings.Index(key, "/"); i != -1 {
+		prefix := key[:i]
+		if len(prefix) > 253 || !labelPrefix.MatchString(prefix) {
+			return false
+		}
+		name = key[i+1:]
+	}
+	return labelName.MatchString(name)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
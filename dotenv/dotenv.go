@@ -0,0 +1,177 @@
+// Package dotenv provides methods for reading and writing .env files and syncing them with an
+// app's config.
+//
+// A .env file has one KEY=value pair per line:
+//
+//    # Comments and blank lines are ignored.
+//    export DATABASE_URL=postgres://db/example  # so are trailing comments
+//    GREETING='Hello, $USER'
+//    CERT="-----BEGIN CERTIFICATE-----
+//    MIIB...
+//    -----END CERTIFICATE-----"
+//    MOTD="line one\nline two"
+//
+// Single-quoted values are literal. Double-quoted values support the escapes \n, \r, \t, \",
+// \$ and \\. Both can span lines. Unquoted values are trimmed, and end at a " #" comment.
+package dotenv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	keyPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	barePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@+,%=-]*$`)
+)
+
+// ErrSyntax is returned when a line of a .env file is invalid. Line is numbered from 1.
+type ErrSyntax struct {
+	Line   int
+	Reason string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf(".env line %d: %s", e.Line, e.Reason)
+}
+
+// Parse parses a .env file. If a key is repeated, the last value is used.
+func Parse(data []byte) (map[string]string, error) {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	values := map[string]string{}
+	for i := 0; i < len(lines); {
+		number := i + 1
+		line := strings.TrimLeft(lines[i], " \t")
+		i++
+
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimLeft(line[len("export"):], " \t")
+		}
+
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return nil, ErrSyntax{Line: number, Reason: fmt.Sprintf("expected \"KEY=value\", got %q", strings.TrimSpace(line))}
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !keyPattern.MatchString(key) {
+			return nil, ErrSyntax{Line: number, Reason: fmt.Sprintf("invalid key %q", key)}
+		}
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			if j := inlineComment(rest); j != -1 {
+				rest = rest[:j]
+			}
+			values[key] = strings.TrimSpace(rest)
+			continue
+		}
+
+		quote, body := rest[0], rest[1:]
+		for {
+			end := closingQuote(body, quote)
+			if end != -1 {
+				if trailing := strings.TrimSpace(body[end+1:]); trailing != "" && !strings.HasPrefix(trailing, "#") {
+					return nil, ErrSyntax{Line: number, Reason: fmt.Sprintf("unexpected %q after the closing quote", trailing)}
+				}
+				body = body[:end]
+				break
+			}
+			if i == len(lines) {
+				return nil, ErrSyntax{Line: number, Reason: fmt.Sprintf("the value of %s has no closing quote", key)}
+			}
+			body += "\n" + lines[i]
+			i++
+		}
+
+		if quote == '"' {
+			body = unescape(body)
+		}
+		values[key] = body
+	}
+
+	return values, nil
+}
+
+// Read reads and parses a .env file.
+func Read(r io.Reader) (map[string]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// inlineComment returns the index of a comment following whitespace, or -1.
+func inlineComment(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote ending a value, skipping escaped double quotes.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+var escapes = map[byte]string{'n': "\n", 'r': "\r", 't': "\t", '"': `"`, '$': "$", '\\': `\`}
+
+func unescape(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if unescaped, ok := escapes[s[i+1]]; ok {
+				b.WriteString(unescaped)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+var quoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// Format writes values in the .env format, sorted by key. Values are double-quoted and escaped
+// unless they only contain characters that are safe unquoted.
+func Format(values map[string]string) []byte {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, key := range keys {
+		value := values[key]
+		if barePattern.MatchString(value) {
+			fmt.Fprintf(&b, "%s=%s\n", key, value)
+		} else {
+			fmt.Fprintf(&b, "%s=\"%s\"\n", key, quoter.Replace(value))
+		}
+	}
+	return b.Bytes()
+}
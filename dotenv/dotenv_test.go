@@ -0,0 +1,216 @@
+package dotenv
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/releasediff"
+)
+
+const dotenvFixture = "# Settings for example-go\r\n" + `
+export DATABASE_URL=postgres://db/example  # the primary
+  PLAIN = spaced value 
+EMPTY=
+HASH=a#b
+SINGLE='Hello, $USER \n'
+DOUBLE="say \"hi\"\tthen\nleave \$5 \\ ok" # comment
+CERT="-----BEGIN-----
+abc
+-----END-----"
+LITERAL='two
+lines'
+PLAIN=overridden
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	actual, err := Parse([]byte(dotenvFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"DATABASE_URL": "postgres://db/example",
+		"PLAIN":        "overridden",
+		"EMPTY":        "",
+		"HASH":         "a#b",
+		"SINGLE":       `Hello, $USER \n`,
+		"DOUBLE":       "say \"hi\"\tthen\nleave $5 \\ ok",
+		"CERT":         "-----BEGIN-----\nabc\n-----END-----",
+		"LITERAL":      "two\nlines",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		dotenv   string
+		expected error
+	}{
+		{"A=1\nB\n", ErrSyntax{Line: 2, Reason: `expected "KEY=value", got "B"`}},
+		{"1A=1\n", ErrSyntax{Line: 1, Reason: `invalid key "1A"`}},
+		{"A=1\nB=\"open\nstill open\n", ErrSyntax{Line: 2, Reason: "the value of B has no closing quote"}},
+		{"A='x' y\n", ErrSyntax{Line: 1, Reason: `unexpected "y" after the closing quote`}},
+	}
+
+	for _, check := range checks {
+		if _, err := Parse([]byte(check.dotenv)); err != check.expected {
+			t.Errorf("Expected %v, Got %v", check.expected, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]string{
+		"URL":   "postgres://user@db:5432/example?ssl=true",
+		"EMPTY": "",
+		"MOTD":  "say \"hi\"\nto $USER # now",
+	}
+
+	expected := `EMPTY=
+MOTD="say \"hi\"\nto \$USER # now"
+URL="postgres://user@db:5432/example?ssl=true"
+`
+	actual := Format(values)
+	if string(actual) != expected {
+		t.Errorf("Expected %s, Got %s", expected, actual)
+	}
+
+	roundTrip, err := Parse(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, roundTrip) {
+		t.Errorf("Expected %v, Got %v", values, roundTrip)
+	}
+}
+
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.URL.Path != "/v2/apps/example-go/config/" {
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+		return
+	}
+
+	if req.Method == "GET" {
+		res.Write([]byte(`{"values": {"FOO": "bar", "OLD": "x", "WORKERS": 4}}`))
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+	f.requests = append(f.requests, string(body))
+	res.WriteHeader(http.StatusCreated)
+	res.Write([]byte(`{}`))
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{"FOO": "baz", "NEW": "1", "WORKERS": "4"}
+
+	plan, err := Import(d, "example-go", values, ImportOptions{Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `=== import to example-go
+  ~ FOO: bar -> baz
+  + NEW=1
+  - OLD
+`
+	if plan.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, plan.String())
+	}
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no changes in a dry run, Got %v", handler.requests)
+	}
+
+	plan, err = Import(d, "example-go", values, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[1].Action != releasediff.Added {
+		t.Errorf("Expected OLD to be kept, Got %v", plan.Changes)
+	}
+
+	if _, err := Import(d, "example-go", values, ImportOptions{Prune: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRequests := []string{
+		`{"values":{"FOO":"baz","NEW":"1"}}`,
+		`{"values":{"FOO":"baz","NEW":"1","OLD":null}}`,
+	}
+	if !reflect.DeepEqual(expectedRequests, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expectedRequests, handler.requests)
+	}
+
+	_, err = Import(d, "example-go", map[string]string{"DEIS_APP": "x"}, ImportOptions{DryRun: true})
+	if expected := (config.ErrReservedKey{Key: "DEIS_APP"}); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := Export(d, "example-go", &b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "FOO=bar\nOLD=x\nWORKERS=4\n"
+	if b.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, b.String())
+	}
+}
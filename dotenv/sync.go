@@ -0,0 +1,129 @@
+package dotenv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/releasediff"
+	"github.com/deis/controller-sdk-go/sensitive"
+)
+
+// ImportOptions controls how a .env file is imported.
+type ImportOptions struct {
+	// Prune unsets config values that aren't in the file.
+	Prune bool
+	// DryRun returns the changes without making them.
+	DryRun bool
+}
+
+// Plan is the changes an import makes to an app's config.
+type Plan struct {
+	App     string               `json:"app"`
+	Changes []releasediff.Change `json:"changes,omitempty"`
+}
+
+// Empty returns true if the import changes nothing.
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the Plan in a readable format, with sensitive values masked.
+func (p Plan) String() string {
+	return p.Render(sensitive.Masker{})
+}
+
+// Render renders the Plan in a readable format, masking values with m.
+func (p Plan) Render(m sensitive.Masker) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== import to %s\n", p.App)
+	if p.Empty() {
+		b.WriteString("no changes\n")
+	}
+	for _, change := range releasediff.MaskChanges(m, p.Changes) {
+		fmt.Fprintf(&b, "  %s\n", change)
+	}
+	return b.String()
+}
+
+// Import sets an app's config values to the values of a .env file in a single release, and
+// returns the changes. Values that are already set are left alone. The keys and values are
+// checked with config.Validate before anything is changed, even in a dry run.
+//
+// This example previews importing a local .env file:
+//
+//    f, err := os.Open(".env")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    values, err := dotenv.Read(f)
+//    f.Close()
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    plan, err := dotenv.Import(client, "example-go", values, dotenv.ImportOptions{DryRun: true})
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    fmt.Print(plan)
+func Import(c *deis.Client, appID string, values map[string]string, opts ImportOptions) (Plan, error) {
+	current, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Plan{}, err
+	}
+
+	before, after := map[string]string{}, map[string]string{}
+	for key, value := range current.Values {
+		before[key] = fmt.Sprint(value)
+		if opts.Prune {
+			continue
+		}
+		after[key] = before[key]
+	}
+	for key, value := range values {
+		after[key] = value
+	}
+
+	plan := Plan{App: appID, Changes: releasediff.DiffMaps(before, after)}
+
+	patch := api.Config{Values: map[string]interface{}{}}
+	for _, change := range plan.Changes {
+		if change.Action == releasediff.Removed {
+			patch.Values[change.Key] = nil
+		} else {
+			patch.Values[change.Key] = change.New
+		}
+	}
+	if err := config.Validate(patch); err != nil {
+		return Plan{}, err
+	}
+
+	if opts.DryRun || plan.Empty() {
+		return plan, nil
+	}
+
+	if _, err := config.Set(c, appID, patch); err != nil && !deis.IsErrAPIMismatch(err) {
+		return Plan{}, err
+	}
+	return plan, nil
+}
+
+// Export writes an app's config values in the .env format, sorted by key. Values that aren't
+// strings are written as they're printed by fmt.
+func Export(c *deis.Client, appID string, w io.Writer) error {
+	current, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return err
+	}
+
+	values := make(map[string]string, len(current.Values))
+	for key, value := range current.Values {
+		values[key] = fmt.Sprint(value)
+	}
+
+	_, err = w.Write(Format(values))
+	return err
+}
@@ -0,0 +1,271 @@
+// Package healthcheck provides methods for building, validating and testing an app's
+// healthchecks.
+//
+// Each process type can have a liveness check, which restarts processes that fail it, and a
+// readiness check, which takes processes that fail it out of the router. A check runs exactly
+// one probe: a command, an HTTP GET request or a TCP connection.
+//
+// This example checks that web serves /healthz before it receives traffic, and restarts
+// worker processes that stop listening on port 9000:
+//
+//    b := healthcheck.NewBuilder()
+//    b.Readiness("web", healthcheck.HTTPGet(8080, "/healthz"))
+//    b.Liveness("worker", healthcheck.TCPSocket(9000))
+//    _, err := b.Set(client, "example-go")
+package healthcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/procfile"
+)
+
+const (
+	// Liveness is the kind of check that restarts a process when it fails.
+	Liveness = "livenessProbe"
+	// Readiness is the kind of check that stops routing to a process when it fails.
+	Readiness = "readinessProbe"
+)
+
+// The defaults of new checks, which are the controller's defaults.
+const (
+	DefaultInitialDelaySeconds = 50
+	DefaultTimeoutSeconds      = 50
+	DefaultPeriodSeconds       = 10
+	DefaultSuccessThreshold    = 1
+	DefaultFailureThreshold    = 3
+)
+
+// ErrInvalidHealthcheck is returned when a healthcheck is invalid.
+type ErrInvalidHealthcheck struct {
+	Type   string
+	Kind   string
+	Reason string
+}
+
+func (e ErrInvalidHealthcheck) Error() string {
+	return fmt.Sprintf("The %s of %s is invalid: %s", e.Kind, e.Type, e.Reason)
+}
+
+// HTTPGet creates a check that sends a GET request to a path and port, and passes if the
+// response status is at least 200 and below 400.
+func HTTPGet(port int, path string, headers ...api.KVPair) *api.Healthcheck {
+	probe := &api.HTTPGetProbe{Path: path, Port: port}
+	for i := range headers {
+		probe.HTTPHeaders = append(probe.HTTPHeaders, &headers[i])
+	}
+
+	h := defaults()
+	h.HTTPGet = probe
+	return h
+}
+
+// TCPSocket creates a check that passes if a TCP connection to a port can be opened.
+func TCPSocket(port int) *api.Healthcheck {
+	h := defaults()
+	h.TCPSocket = &api.TCPSocketProbe{Port: port}
+	return h
+}
+
+// Exec creates a check that runs a command in the process's container, and passes if the
+// command exits with 0.
+func Exec(command ...string) *api.Healthcheck {
+	h := defaults()
+	h.Exec = &api.ExecProbe{Command: command}
+	return h
+}
+
+func defaults() *api.Healthcheck {
+	return &api.Healthcheck{
+		InitialDelaySeconds: DefaultInitialDelaySeconds,
+		TimeoutSeconds:      DefaultTimeoutSeconds,
+		PeriodSeconds:       DefaultPeriodSeconds,
+		SuccessThreshold:    DefaultSuccessThreshold,
+		FailureThreshold:    DefaultFailureThreshold,
+	}
+}
+
+// Validate checks a healthcheck of a process type. A check must have exactly one probe, no
+// negative periods or thresholds, and valid ports. A liveness check must succeed once to pass,
+// so its success threshold can only be 1. A nil check unsets the healthcheck and is valid.
+func Validate(procType, kind string, h *api.Healthcheck) error {
+	if err := procfile.ValidateType(procType); err != nil {
+		return err
+	}
+
+	invalid := func(reason string, args ...interface{}) error {
+		return ErrInvalidHealthcheck{Type: procType, Kind: kind, Reason: fmt.Sprintf(reason, args...)}
+	}
+
+	if kind != Liveness && kind != Readiness {
+		return invalid("the kind must be %s or %s", Liveness, Readiness)
+	}
+	if h == nil {
+		return nil
+	}
+
+	if reason := checkProbe(h); reason != "" {
+		return invalid("%s", reason)
+	}
+	if kind == Liveness && h.SuccessThreshold > 1 {
+		return invalid("successThreshold must be 1 for a liveness check")
+	}
+	return nil
+}
+
+// checkProbe returns why a check's probe is invalid, or "" if it's valid. These are the checks
+// that don't depend on the process type or kind, so Run makes them too.
+func checkProbe(h *api.Healthcheck) string {
+	probes := 0
+	if h.Exec != nil {
+		probes++
+	}
+	if h.HTTPGet != nil {
+		probes++
+	}
+	if h.TCPSocket != nil {
+		probes++
+	}
+	if probes != 1 {
+		return fmt.Sprintf("it needs exactly one exec, httpGet or tcpSocket probe, not %d", probes)
+	}
+
+	fields := []struct {
+		name  string
+		value int
+	}{
+		{"initialDelaySeconds", h.InitialDelaySeconds},
+		{"timeoutSeconds", h.TimeoutSeconds},
+		{"periodSeconds", h.PeriodSeconds},
+		{"successThreshold", h.SuccessThreshold},
+		{"failureThreshold", h.FailureThreshold},
+	}
+	for _, field := range fields {
+		if field.value < 0 {
+			return fmt.Sprintf("%s is negative", field.name)
+		}
+	}
+
+	switch {
+	case h.Exec != nil:
+		if len(h.Exec.Command) == 0 {
+			return "the exec probe has no command"
+		}
+	case h.HTTPGet != nil:
+		if !validPort(h.HTTPGet.Port) {
+			return fmt.Sprintf("port %d is not between 1 and 65535", h.HTTPGet.Port)
+		}
+		if h.HTTPGet.Path != "" && !strings.HasPrefix(h.HTTPGet.Path, "/") {
+			return fmt.Sprintf("path %q does not start with /", h.HTTPGet.Path)
+		}
+		for _, header := range h.HTTPGet.HTTPHeaders {
+			if header == nil || header.Name == "" {
+				return "an HTTP header has no name"
+			}
+		}
+	case h.TCPSocket != nil:
+		if !validPort(h.TCPSocket.Port) {
+			return fmt.Sprintf("port %d is not between 1 and 65535", h.TCPSocket.Port)
+		}
+	}
+
+	return ""
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// ValidateAll checks every healthcheck of a config, sorted by process type and kind.
+func ValidateAll(checks map[string]*api.Healthchecks) error {
+	var procTypes []string
+	for procType := range checks {
+		procTypes = append(procTypes, procType)
+	}
+	sort.Strings(procTypes)
+
+	for _, procType := range procTypes {
+		if checks[procType] == nil {
+			continue
+		}
+
+		var kinds []string
+		for kind := range *checks[procType] {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			if err := Validate(procType, kind, (*checks[procType])[kind]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Builder builds the healthchecks of an app's process types, to set them in a single release.
+type Builder struct {
+	checks map[string]*api.Healthchecks
+}
+
+// NewBuilder creates a Builder with no healthchecks.
+func NewBuilder() *Builder {
+	return &Builder{checks: map[string]*api.Healthchecks{}}
+}
+
+// Liveness sets the liveness check of a process type.
+func (b *Builder) Liveness(procType string, h *api.Healthcheck) *Builder {
+	return b.add(procType, Liveness, h)
+}
+
+// Readiness sets the readiness check of a process type.
+func (b *Builder) Readiness(procType string, h *api.Healthcheck) *Builder {
+	return b.add(procType, Readiness, h)
+}
+
+// Unset removes the checks of the given kinds from a process type. Without kinds, both are
+// removed.
+func (b *Builder) Unset(procType string, kinds ...string) *Builder {
+	if len(kinds) == 0 {
+		kinds = []string{Liveness, Readiness}
+	}
+	for _, kind := range kinds {
+		b.add(procType, kind, nil)
+	}
+	return b
+}
+
+func (b *Builder) add(procType, kind string, h *api.Healthcheck) *Builder {
+	if b.checks[procType] == nil {
+		b.checks[procType] = &api.Healthchecks{}
+	}
+	(*b.checks[procType])[kind] = h
+	return b
+}
+
+// Config validates the healthchecks and returns them as a config patch.
+func (b *Builder) Config() (api.Config, error) {
+	if len(b.checks) == 0 {
+		return api.Config{}, config.ErrNoChanges
+	}
+	if err := ValidateAll(b.checks); err != nil {
+		return api.Config{}, err
+	}
+	return api.Config{Healthcheck: b.checks}, nil
+}
+
+// Set validates the healthchecks and sets them on an app, creating a new release. Checks of
+// other process types and kinds are left alone.
+func (b *Builder) Set(c *deis.Client, appID string) (api.Config, error) {
+	patch, err := b.Config()
+	if err != nil {
+		return api.Config{}, err
+	}
+	return config.Set(c, appID, patch)
+}
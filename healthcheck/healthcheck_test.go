@@ -0,0 +1,235 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+const configFixture string = `
+{
+    "owner": "test",
+    "app": "example-go",
+    "values": {},
+    "memory": {},
+    "cpu": {},
+    "tags": {},
+    "registry": {},
+    "created": "2014-01-01T00:00:00UTC",
+    "updated": "2014-01-01T00:00:00UTC",
+    "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"
+}
+`
+
+type fakeHTTPServer struct {
+	patches []string
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	if req.URL.Path == "/v2/apps/example-go/config/" && req.Method == "POST" {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fmt.Println(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		f.patches = append(f.patches, string(body))
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(configFixture))
+		return
+	}
+
+	fmt.Printf("Unrecognized URL %s\n", req.URL)
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []struct {
+		kind  string
+		check *api.Healthcheck
+	}{
+		{Liveness, HTTPGet(8080, "/healthz", api.KVPair{Name: "X-Probe", Value: "1"})},
+		{Readiness, TCPSocket(5000)},
+		{Readiness, Exec("/bin/check", "--ready")},
+		{Liveness, nil},
+		{Readiness, &api.Healthcheck{TCPSocket: &api.TCPSocketProbe{Port: 1}}},
+	}
+	for _, check := range valid {
+		if err := Validate("web", check.kind, check.check); err != nil {
+			t.Errorf("Expected %v to be valid, Got %v", check.check, err)
+		}
+	}
+
+	negative := TCPSocket(5000)
+	negative.PeriodSeconds = -1
+	several := HTTPGet(8080, "/")
+	several.TCPSocket = &api.TCPSocketProbe{Port: 8080}
+	threshold := HTTPGet(8080, "/")
+	threshold.SuccessThreshold = 2
+
+	invalid := []struct {
+		kind     string
+		check    *api.Healthcheck
+		expected string
+	}{
+		{Liveness, negative, "periodSeconds is negative"},
+		{Liveness, several, "it needs exactly one exec, httpGet or tcpSocket probe, not 2"},
+		{Liveness, &api.Healthcheck{}, "it needs exactly one exec, httpGet or tcpSocket probe, not 0"},
+		{Liveness, threshold, "successThreshold must be 1 for a liveness check"},
+		{Readiness, TCPSocket(0), "port 0 is not between 1 and 65535"},
+		{Readiness, HTTPGet(70000, "/"), "port 70000 is not between 1 and 65535"},
+		{Readiness, HTTPGet(80, "healthz"), `path "healthz" does not start with /`},
+		{Readiness, HTTPGet(80, "/", api.KVPair{Value: "1"}), "an HTTP header has no name"},
+		{Readiness, Exec(), "the exec probe has no command"},
+		{"startupProbe", TCPSocket(80), "the kind must be livenessProbe or readinessProbe"},
+	}
+	for _, check := range invalid {
+		expected := ErrInvalidHealthcheck{Type: "web", Kind: check.kind, Reason: check.expected}
+		if err := Validate("web", check.kind, check.check); err != expected {
+			t.Errorf("Expected %v, Got %v", expected, err)
+		}
+	}
+
+	// A valid readiness check can't be used as a liveness check.
+	if err := Validate("web", Readiness, threshold); err != nil {
+		t.Errorf("Expected nil, Got %v", err)
+	}
+
+	if err := Validate("Web", Liveness, TCPSocket(80)); err == nil {
+		t.Error("Expected an error for an invalid process type")
+	}
+}
+
+func TestBuilderSet(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readiness := HTTPGet(8080, "/healthz")
+	readiness.InitialDelaySeconds = 5
+
+	b := NewBuilder().Readiness("web", readiness).Unset("worker", Liveness)
+	if _, err := b.Set(deis, "example-go"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"healthcheck":{"web":{"readinessProbe":{"initialDelaySeconds":5,"timeoutSeconds":50,"periodSeconds":10,` +
+		`"successThreshold":1,"failureThreshold":3,"httpGet":{"path":"/healthz","port":8080}}},"worker":{"livenessProbe":null}}}`
+	if len(handler.patches) != 1 || handler.patches[0] != expected {
+		t.Errorf("Expected %s, Got %v", expected, handler.patches)
+	}
+
+	b.Liveness("web", TCPSocket(-1))
+	if _, err := b.Set(deis, "example-go"); err == nil {
+		t.Error("Expected an error for an invalid port")
+	}
+	if len(handler.patches) != 1 {
+		t.Errorf("Expected 1 patch, Got %v", handler.patches)
+	}
+
+	if _, err := NewBuilder().Set(deis, "example-go"); err == nil {
+		t.Error("Expected an error for an empty builder")
+	}
+}
+
+func TestRunHTTPGet(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/healthz" && req.Header.Get("X-Probe") == "1":
+			res.WriteHeader(http.StatusOK)
+		case req.URL.Path == "/moved":
+			http.Redirect(res, req, "/missing", http.StatusFound)
+		default:
+			res.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := HTTPGet(8080, "/healthz", api.KVPair{Name: "X-Probe", Value: "1"})
+	if err := Run(context.Background(), check, u.Host); err != nil {
+		t.Errorf("Expected nil, Got %v", err)
+	}
+
+	if err := Run(context.Background(), HTTPGet(8080, "/moved"), u.Host); err != nil {
+		t.Errorf("Expected a redirect to pass, Got %v", err)
+	}
+
+	expected := ErrProbeFailed{Address: server.URL + "/healthz", Reason: "503 Service Unavailable"}
+	if err := Run(context.Background(), HTTPGet(8080, "/healthz"), u.Host); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+
+	if err := Run(context.Background(), Exec("true"), u.Host); err != ErrExecProbe {
+		t.Errorf("Expected %v, Got %v", ErrExecProbe, err)
+	}
+	if err := Run(context.Background(), &api.Healthcheck{}, u.Host); err != ErrNoProbe {
+		t.Errorf("Expected %v, Got %v", ErrNoProbe, err)
+	}
+
+	both := HTTPGet(8080, "/healthz")
+	both.TCPSocket = &api.TCPSocketProbe{Port: 8080}
+	expectedInvalid := ErrInvalidProbe{Reason: "it needs exactly one exec, httpGet or tcpSocket probe, not 2"}
+	if err := Run(context.Background(), both, u.Host); err != expectedInvalid {
+		t.Errorf("Expected %v, Got %v", expectedInvalid, err)
+	}
+
+	expectedInvalid = ErrInvalidProbe{Reason: "port -1 is not between 1 and 65535"}
+	if err := Run(context.Background(), HTTPGet(-1, "/healthz"), u.Host); err != expectedInvalid {
+		t.Errorf("Expected %v, Got %v", expectedInvalid, err)
+	}
+
+	negative := HTTPGet(8080, "/healthz")
+	negative.TimeoutSeconds = -1
+	expectedInvalid = ErrInvalidProbe{Reason: "timeoutSeconds is negative"}
+	if err := Run(context.Background(), negative, u.Host); err != expectedInvalid {
+		t.Errorf("Expected %v, Got %v", expectedInvalid, err)
+	}
+}
+
+func TestRunTCPSocket(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+
+	if err := Run(context.Background(), TCPSocket(port), "127.0.0.1"); err != nil {
+		t.Errorf("Expected nil, Got %v", err)
+	}
+
+	l.Close()
+	err = Run(context.Background(), TCPSocket(port), "127.0.0.1")
+	if _, ok := err.(ErrProbeFailed); !ok {
+		t.Errorf("Expected ErrProbeFailed, Got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deis/controller-sdk-go/api"
+)
+
+var (
+	// ErrNoProbe is returned when running a healthcheck without a probe.
+	ErrNoProbe = errors.New("The healthcheck has no probe to run")
+	// ErrExecProbe is returned when running an exec probe, which only runs in a container.
+	ErrExecProbe = errors.New("Exec probes can only run in the app's containers")
+)
+
+// ErrInvalidProbe is returned when running a healthcheck that Validate would reject.
+type ErrInvalidProbe struct {
+	Reason string
+}
+
+func (e ErrInvalidProbe) Error() string {
+	return fmt.Sprintf("The healthcheck is invalid: %s", e.Reason)
+}
+
+// ErrProbeFailed is returned when a probe runs and fails.
+type ErrProbeFailed struct {
+	Address string
+	Reason  string
+}
+
+func (e ErrProbeFailed) Error() string {
+	return fmt.Sprintf("The probe of %s failed: %s", e.Address, e.Reason)
+}
+
+// Run runs the probe of a healthcheck once against a local or forwarded address, so a check can
+// be tried before it's rolled out. The check is validated first, like Validate does, and an
+// ErrInvalidProbe is returned instead of running an invalid check. The address is a host, which is probed on the check's port,
+// or a host and port, which replaces the check's port. The probe times out after the check's
+// timeout, unless ctx is done first. Like the kubelet, an HTTP probe passes on a status of at
+// least 200 and below 400, without following redirects.
+//
+// This example checks that a locally running web process is ready:
+//
+//    err := healthcheck.Run(context.Background(), healthcheck.HTTPGet(8080, "/healthz"), "localhost")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+func Run(ctx context.Context, h *api.Healthcheck, address string) error {
+	if h == nil || (h.Exec == nil && h.HTTPGet == nil && h.TCPSocket == nil) {
+		return ErrNoProbe
+	}
+	if reason := checkProbe(h); reason != "" {
+		return ErrInvalidProbe{Reason: reason}
+	}
+	if h.Exec != nil {
+		return ErrExecProbe
+	}
+	if h.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(h.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	switch {
+	case h.HTTPGet != nil:
+		return runHTTPGet(ctx, h.HTTPGet, hostPort(address, h.HTTPGet.Port))
+	case h.TCPSocket != nil:
+		return runTCPSocket(ctx, hostPort(address, h.TCPSocket.Port))
+	}
+	return ErrNoProbe
+}
+
+func runHTTPGet(ctx context.Context, probe *api.HTTPGetProbe, address string) error {
+	u := "http://" + address + probe.Path
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	for _, header := range probe.HTTPHeaders {
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ErrProbeFailed{Address: u, Reason: err.Error()}
+	}
+	res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		return ErrProbeFailed{Address: u, Reason: res.Status}
+	}
+	return nil
+}
+
+func runTCPSocket(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return ErrProbeFailed{Address: address, Reason: err.Error()}
+	}
+	return conn.Close()
+}
+
+// hostPort adds the port to an address without one.
+func hostPort(address string, port int) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
+}
@@ -0,0 +1,289 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/releasediff"
+	"github.com/deis/controller-sdk-go/sensitive"
+)
+
+// defaultResults is the number of releases walked by ConfigTimeline when no limit is given.
+const defaultResults = 1000
+
+// KeyChange is a change to one config key in a release.
+type KeyChange struct {
+	Version int       `json:"version"`
+	Actor   string    `json:"actor"`
+	Time    time.Time `json:"time"`
+	Subject Subject   `json:"subject"`
+	Key     string    `json:"key"`
+	Action  Action    `json:"action"`
+	// Resolved is true if the configs around the change were resolved, so Old and New hold
+	// the values before and after it.
+	Resolved bool   `json:"resolved"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+}
+
+// String displays the KeyChange in a readable format.
+func (c KeyChange) String() string {
+	s := fmt.Sprintf("v%d %s %s %s", c.Version, formatTime(c.Time), c.Actor, c.Action)
+	if !c.Resolved {
+		return s
+	}
+	switch c.Action {
+	case Added:
+		return s + " " + c.New
+	case Changed:
+		return fmt.Sprintf("%s %s -> %s", s, c.Old, c.New)
+	case Deleted:
+		return s + " " + c.Old
+	}
+	return s
+}
+
+// ConfigOptions controls how a config timeline is built.
+type ConfigOptions struct {
+	// Results is the number of releases walked, newest first. Zero walks up to 1000 releases.
+	Results int
+	// Resolver resolves the configs of releases, to fill in old and new values. If it is nil,
+	// only the app's current config is resolved, so only the values around the latest release
+	// are known.
+	Resolver releasediff.ConfigResolver
+	// Masker masks sensitive values. The zero Masker masks them; set Reveal to keep them.
+	Masker sensitive.Masker
+}
+
+// Timeline is the changes to an app's config keys, oldest first.
+type Timeline struct {
+	App     string      `json:"app"`
+	Changes []KeyChange `json:"changes"`
+}
+
+// For returns the changes to a key of a subject, oldest first.
+func (t Timeline) For(subject Subject, key string) []KeyChange {
+	var changes []KeyChange
+	for _, change := range t.Changes {
+		if change.Subject == subject && change.Key == key {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Key returns the changes to an environment variable, oldest first.
+func (t Timeline) Key(key string) []KeyChange {
+	return t.For(Env, key)
+}
+
+// String renders the Timeline in a readable format, grouped by key.
+func (t Timeline) String() string {
+	var groups []group
+	seen := map[group]bool{}
+	for _, change := range t.Changes {
+		g := group{change.Subject, change.Key}
+		if !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+	sort.Sort(byKey(groups))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== %s config history\n", t.App)
+	if len(t.Changes) == 0 {
+		b.WriteString("no changes\n")
+	}
+	for _, g := range groups {
+		if g.subject == Env {
+			fmt.Fprintf(&b, "%s\n", g.key)
+		} else {
+			fmt.Fprintf(&b, "%s %s\n", g.subject, g.key)
+		}
+		for _, change := range t.For(g.subject, g.key) {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+	return b.String()
+}
+
+type group struct {
+	subject Subject
+	key     string
+}
+
+// byKey sorts groups by subject, then key.
+type byKey []group
+
+func (g byKey) Len() int      { return len(g) }
+func (g byKey) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
+func (g byKey) Less(i, j int) bool {
+	if g[i].subject != g[j].subject {
+		return g[i].subject < g[j].subject
+	}
+	return g[i].key < g[j].key
+}
+
+// ConfigTimeline walks an app's releases and builds a timeline of the changes to each config
+// key from the release summaries. The values before and after a change to an environment
+// variable, tag or registry setting are filled in when opts.Resolver resolves the configs of
+// the release and the one before it, and are masked by opts.Masker.
+//
+// This example shows who changed DATABASE_URL and when:
+//
+//    timeline, err := history.ConfigTimeline(client, "example-go", history.ConfigOptions{})
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    for _, change := range timeline.Key("DATABASE_URL") {
+//        fmt.Println(change)
+//    }
+func ConfigTimeline(c *deis.Client, appID string, opts ConfigOptions) (Timeline, error) {
+	if opts.Results == 0 {
+		opts.Results = defaultResults
+	}
+
+	entries, err := List(c, appID, opts.Results)
+	if err != nil {
+		return Timeline{}, err
+	}
+
+	if opts.Resolver == nil {
+		cfg, err := config.List(c, appID)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Timeline{}, err
+		}
+		opts.Resolver = latest(cfg)
+	}
+
+	r := configResolver{c: c, appID: appID, resolver: opts.Resolver, configs: map[string]*api.Config{}}
+	timeline := Timeline{App: appID}
+
+	// Entries are newest first, so the release before entries[i] is entries[i+1].
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		var before, after *api.Config
+		fetched := false
+		for _, change := range entry.Changes {
+			if change.Action != Added && change.Action != Changed && change.Action != Deleted {
+				continue
+			}
+
+			if !fetched {
+				fetched = true
+				if i+1 < len(entries) {
+					if before, err = r.config(entries[i+1].Config); err != nil {
+						return Timeline{}, err
+					}
+				}
+				if after, err = r.config(entry.Config); err != nil {
+					return Timeline{}, err
+				}
+			}
+
+			for _, key := range change.Keys {
+				kc := KeyChange{
+					Version: entry.Version,
+					Actor:   entry.Actor,
+					Time:    entry.Created,
+					Subject: change.Subject,
+					Key:     key,
+					Action:  change.Action,
+				}
+
+				if resolved(change.Action, before, after) && valuesOf(kc.Subject, after) != nil {
+					kc.Resolved = true
+					kc.Old, kc.New = opts.Masker.Change(key, value(kc.Subject, before, key), value(kc.Subject, after, key))
+				}
+				timeline.Changes = append(timeline.Changes, kc)
+			}
+		}
+	}
+
+	return timeline, nil
+}
+
+// resolved returns true if the configs a change's values come from are known. An added key
+// has no old value, so only the config after it is needed.
+func resolved(action Action, before, after *api.Config) bool {
+	if action == Added {
+		return after != nil
+	}
+	return before != nil && after != nil
+}
+
+// valuesOf returns the values of a subject that can be shown per key, or nil.
+func valuesOf(subject Subject, cfg *api.Config) map[string]interface{} {
+	if cfg == nil {
+		return nil
+	}
+	switch subject {
+	case Env:
+		return nonNil(cfg.Values)
+	case Tags:
+		return nonNil(cfg.Tags)
+	case Registry:
+		return nonNil(cfg.Registry)
+	}
+	return nil
+}
+
+func nonNil(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+func value(subject Subject, cfg *api.Config, key string) string {
+	v, ok := valuesOf(subject, cfg)[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// latest resolves an app's latest config, which it was created with, without contacting the
+// controller again.
+type latest api.Config
+
+func (l latest) Config(c *deis.Client, appID string, uuid string) (api.Config, error) {
+	if l.UUID != uuid {
+		return api.Config{}, releasediff.ErrConfigUnavailable{UUID: uuid}
+	}
+	return api.Config(l), nil
+}
+
+// configResolver caches the configs resolved while walking releases. A config that is
+// unavailable is cached as nil.
+type configResolver struct {
+	c        *deis.Client
+	appID    string
+	resolver releasediff.ConfigResolver
+	configs  map[string]*api.Config
+}
+
+func (r configResolver) config(uuid string) (*api.Config, error) {
+	if cfg, ok := r.configs[uuid]; ok {
+		return cfg, nil
+	}
+
+	cfg, err := r.resolver.Config(r.c, r.appID, uuid)
+	if _, ok := err.(releasediff.ErrConfigUnavailable); ok {
+		r.configs[uuid] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.configs[uuid] = &cfg
+	return &cfg, nil
+}
@@ -0,0 +1,135 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader is the first row written by WriteCSV.
+var csvHeader = []string{"version", "created", "actor", "changes", "build", "config", "summary"}
+
+// describe joins the changes of an entry into a single line.
+func describe(e Entry) string {
+	parts := make([]string, len(e.Changes))
+	for i, change := range e.Changes {
+		parts[i] = change.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteCSV writes entries as CSV with a header row, one row per release.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		row := []string{
+			strconv.Itoa(e.Version),
+			formatTime(e.Created),
+			e.Actor,
+			describe(e),
+			e.Build,
+			e.Config,
+			e.Summary,
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSONLines writes entries as JSON, one release per line.
+func WriteJSONLines(w io.Writer, entries []Entry) error {
+	encoder := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes entries as a Markdown changelog with a section per release.
+func WriteMarkdown(w io.Writer, appID string, entries []Entry) error {
+	if _, err := fmt.Fprintf(w, "# Changelog for %s\n", appID); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		heading := fmt.Sprintf("\n## v%d", e.Version)
+		if !e.Created.IsZero() {
+			heading += " - " + e.Created.UTC().Format("2006-01-02 15:04 MST")
+		}
+		if _, err := fmt.Fprintf(w, "%s\n\n", heading); err != nil {
+			return err
+		}
+
+		if len(e.Changes) == 0 {
+			if _, err := fmt.Fprintf(w, "- %s\n", markdownEscape(e.Summary)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, change := range e.Changes {
+			line := fmt.Sprintf("- **%s** %s\n", markdownEscape(e.Actor), markdownChange(change))
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// markdownChange displays a change with its keys and image as code.
+func markdownChange(c Change) string {
+	code := func(s string) string { return "`" + strings.Replace(s, "`", "'", -1) + "`" }
+
+	switch c.Action {
+	case Deployed:
+		return "deployed " + code(c.Image)
+	case Unknown:
+		return markdownEscape(c.Text)
+	case Added, Changed, Deleted:
+		keys := make([]string, len(c.Keys))
+		for i, key := range c.Keys {
+			keys[i] = code(key)
+		}
+		s := string(c.Action)
+		if c.Subject != Env {
+			s += " " + string(c.Subject)
+			if len(keys) > 0 {
+				s += " for"
+			}
+		}
+		if len(keys) > 0 {
+			s += " " + strings.Join(keys, ", ")
+		}
+		return s
+	default:
+		return c.String()
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,271 @@
+// Package history provides a structured view of an app's release history.
+//
+// The controller describes each release with a free-text summary, such as
+// "bob added FOO, BAR, changed BAZ". List parses these summaries into Changes and converts
+// release timestamps to times, and the exporters write the history as CSV, JSON lines or a
+// Markdown changelog. ConfigTimeline regroups the history by config key.
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+	deistime "github.com/deis/controller-sdk-go/pkg/time"
+	"github.com/deis/controller-sdk-go/releases"
+)
+
+// Action is what a change did.
+type Action string
+
+const (
+	// Created is the app's initial release.
+	Created Action = "created"
+	// Deployed is a new build. The change's Image is the image or git sha deployed.
+	Deployed Action = "deployed"
+	// RolledBack is a rollback. The change's Version is the release rolled back to.
+	RolledBack Action = "rolled back"
+	// Added, Changed and Deleted are changes to the keys of a subject.
+	Added   Action = "added"
+	Changed Action = "changed"
+	Deleted Action = "deleted"
+	// Unknown is a part of a summary that couldn't be parsed. The change's Text holds it.
+	Unknown Action = "unknown"
+)
+
+// Subject is the part of an app's config that a change affected.
+type Subject string
+
+const (
+	// Env is the app's environment variables. It is the subject of "added FOO".
+	Env Subject = "env"
+	// Limits are the memory and CPU limits. The controller names the resources that changed,
+	// "memory" and "cpu", rather than the process types.
+	Limits Subject = "limits"
+	// Tags are the node tags of process types.
+	Tags Subject = "tags"
+	// Registry is the app's private registry credentials.
+	Registry Subject = "registry"
+	// Healthchecks are the healthchecks of process types.
+	Healthchecks Subject = "healthchecks"
+)
+
+// subjects are the phrases that follow a verb in the controller's release summaries, such as
+// "added tag" or "changed limits for", and the subjects they name. Longer phrases come first,
+// so the longest phrase that matches is used.
+var subjects = []struct {
+	phrase  string
+	subject Subject
+}{
+	{"healthcheck info for proc type", Healthchecks},
+	{"healthcheck info", Healthchecks},
+	{"registry info", Registry},
+	{"limits for", Limits},
+	{"tag", Tags},
+}
+
+// Change is one thing a release did.
+type Change struct {
+	Action  Action   `json:"action"`
+	Subject Subject  `json:"subject,omitempty"`
+	Keys    []string `json:"keys,omitempty"`
+	Image   string   `json:"image,omitempty"`
+	Version int      `json:"version,omitempty"`
+	Text    string   `json:"text,omitempty"`
+}
+
+// String displays the Change in a readable format.
+func (c Change) String() string {
+	switch c.Action {
+	case Created:
+		return "created initial release"
+	case Deployed:
+		return "deployed " + c.Image
+	case RolledBack:
+		return fmt.Sprintf("rolled back to v%d", c.Version)
+	case Unknown:
+		return c.Text
+	}
+
+	s := string(c.Action)
+	if c.Subject != Env {
+		s += " " + string(c.Subject)
+		if len(c.Keys) > 0 {
+			s += " for"
+		}
+	}
+	if len(c.Keys) > 0 {
+		s += " " + strings.Join(c.Keys, ", ")
+	}
+	return s
+}
+
+// Entry is a release with its summary parsed.
+type Entry struct {
+	Version int       `json:"version"`
+	Actor   string    `json:"actor"`
+	Changes []Change  `json:"changes"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Build   string    `json:"build,omitempty"`
+	Config  string    `json:"config"`
+	Summary string    `json:"summary"`
+}
+
+// List retrieves an app's releases, newest first, and parses them.
+func List(c *deis.Client, appID string, results int) ([]Entry, error) {
+	rs, _, err := releases.List(c, appID, results)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(rs))
+	for i, r := range rs {
+		if entries[i], err = FromRelease(r); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// FromRelease parses a release's summary and timestamps.
+func FromRelease(r api.Release) (Entry, error) {
+	created, err := ParseTime(r.Created)
+	if err != nil {
+		return Entry{}, err
+	}
+	updated, err := ParseTime(r.Updated)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	actor, changes := ParseSummary(r.Summary)
+	return Entry{
+		Version: r.Version,
+		Actor:   actor,
+		Changes: changes,
+		Created: created,
+		Updated: updated,
+		Build:   r.Build,
+		Config:  r.Config,
+		Summary: r.Summary,
+	}, nil
+}
+
+// ParseTime parses a timestamp returned by the controller. An empty string is the zero time.
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t := deistime.Time{}
+	if err := t.UnmarshalText([]byte(s)); err != nil {
+		return time.Time{}, err
+	}
+	return *t.Time, nil
+}
+
+// ParseSummary splits a release summary into the user who made the release and its changes.
+// Summaries are written by the controller when it saves a release. They list changes
+// separated by commas or "and", repeating the user before each kind of change, such as
+// "bob deployed deis/example-go:v2 and bob added FOO, BAR" or "bob added tag zone". Parts
+// that aren't recognized are returned as Unknown changes.
+func ParseSummary(summary string) (string, []Change) {
+	fields := strings.SplitN(strings.TrimSpace(summary), " ", 2)
+	if len(fields) < 2 {
+		return fields[0], nil
+	}
+	actor := fields[0]
+
+	var changes []Change
+	for _, part := range splitParts(fields[1]) {
+		// Each kind of change after the first starts with the user again.
+		clause := part
+		if strings.HasPrefix(part, actor+" ") {
+			clause = strings.TrimPrefix(part, actor+" ")
+		}
+		if change, ok := parseClause(clause); ok {
+			changes = append(changes, change)
+			continue
+		}
+
+		// Single words continue the keys of the previous change, as in "added FOO, BAR".
+		n := len(changes)
+		if n > 0 && changes[n-1].Action != Unknown && len(changes[n-1].Keys) > 0 && !strings.Contains(part, " ") {
+			changes[n-1].Keys = append(changes[n-1].Keys, part)
+			continue
+		}
+		changes = append(changes, Change{Action: Unknown, Text: part})
+	}
+
+	return actor, changes
+}
+
+// splitParts splits a summary on commas and "and".
+func splitParts(s string) []string {
+	var parts []string
+	for _, comma := range strings.Split(s, ",") {
+		for _, part := range strings.Split(comma, " and ") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+// parseClause parses a part of a summary that starts with a verb. A verb followed by a single
+// config key, as in "added FOO", changes the app's environment. Anything else that isn't a
+// known subject isn't parsed.
+func parseClause(part string) (Change, bool) {
+	switch {
+	case part == "created initial release":
+		return Change{Action: Created}, true
+	case strings.HasPrefix(part, "deployed "):
+		return Change{Action: Deployed, Image: strings.TrimPrefix(part, "deployed ")}, true
+	case strings.HasPrefix(part, "rolled back to v"):
+		version, err := strconv.Atoi(strings.TrimPrefix(part, "rolled back to v"))
+		if err != nil {
+			return Change{}, false
+		}
+		return Change{Action: RolledBack, Version: version}, true
+	}
+
+	words := strings.SplitN(part, " ", 2)
+	if len(words) < 2 {
+		return Change{}, false
+	}
+
+	var action Action
+	switch words[0] {
+	case "added":
+		action = Added
+	case "changed":
+		action = Changed
+	case "deleted":
+		action = Deleted
+	default:
+		return Change{}, false
+	}
+
+	rest := strings.TrimSpace(words[1])
+	for _, s := range subjects {
+		if rest != s.phrase && !strings.HasPrefix(rest, s.phrase+" ") {
+			continue
+		}
+		change := Change{Action: action, Subject: s.subject}
+		if key := strings.TrimSpace(strings.TrimPrefix(rest, s.phrase)); key != "" {
+			change.Keys = []string{key}
+		}
+		return change, true
+	}
+
+	if _, invalid := config.ValidateKey(rest).(config.ErrInvalidKey); invalid {
+		return Change{}, false
+	}
+	return Change{Action: action, Subject: Env, Keys: []string{rest}}, true
+}
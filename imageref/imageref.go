@@ -0,0 +1,172 @@
+// Package imageref provides methods for parsing Docker image references and pinning them to
+// their immutable digests.
+//
+// A reference follows the Docker reference grammar:
+//
+//    [registry/]repository[:tag][@digest]
+//
+// such as "deis/example-go", "quay.io/deis/example-go:v2" or
+// "localhost:5000/example-go@sha256:<hex>". The first component is a registry only if it
+// contains a "." or ":", or is "localhost".
+package imageref
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is the registry of references which don't name one.
+	DefaultRegistry = "docker.io"
+	// DefaultTag is the tag of references which have neither a tag nor a digest.
+	DefaultTag = "latest"
+
+	// officialRepository is the namespace of single component repositories on DefaultRegistry.
+	officialRepository = "library"
+	// maxNameLength is the longest registry and repository the Docker grammar allows.
+	maxNameLength = 255
+)
+
+var (
+	registryPattern  = regexp.MustCompile(`^(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?$`)
+	componentPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*$`)
+	tagPattern       = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestPattern    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+// digestLengths are the hex lengths of the digest algorithms registries use.
+var digestLengths = map[string]int{"sha256": 64, "sha384": 96, "sha512": 128}
+
+// ErrInvalidReference is returned when an image reference doesn't follow the Docker grammar.
+type ErrInvalidReference struct {
+	Reference string
+	Reason    string
+}
+
+func (e ErrInvalidReference) Error() string {
+	return fmt.Sprintf("Invalid image reference %q: %s", e.Reference, e.Reason)
+}
+
+// Reference is a parsed image reference. Registry, Tag and Digest are empty if not given.
+type Reference struct {
+	Registry   string `json:"registry,omitempty"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// Parse parses an image reference.
+func Parse(s string) (Reference, error) {
+	invalid := func(reason string) (Reference, error) {
+		return Reference{}, ErrInvalidReference{Reference: s, Reason: reason}
+	}
+
+	if s == "" {
+		return invalid("the reference is empty")
+	}
+
+	ref := Reference{}
+	name := s
+
+	if i := strings.Index(name, "@"); i != -1 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if err := validateDigest(ref.Digest); err != "" {
+			return invalid(err)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if !tagPattern.MatchString(ref.Tag) {
+			return invalid(fmt.Sprintf("invalid tag %q", ref.Tag))
+		}
+	}
+
+	if len(name) > maxNameLength {
+		return invalid(fmt.Sprintf("the name is longer than %d characters", maxNameLength))
+	}
+
+	ref.Repository = name
+	if i := strings.Index(name, "/"); i != -1 && isRegistry(name[:i]) {
+		ref.Registry, ref.Repository = name[:i], name[i+1:]
+		if !registryPattern.MatchString(ref.Registry) {
+			return invalid(fmt.Sprintf("invalid registry %q", ref.Registry))
+		}
+	}
+
+	if ref.Repository == "" {
+		return invalid("the repository is empty")
+	}
+	for _, component := range strings.Split(ref.Repository, "/") {
+		if !componentPattern.MatchString(component) {
+			if strings.ToLower(component) != component {
+				return invalid("the repository must be lowercase")
+			}
+			return invalid(fmt.Sprintf("invalid repository component %q", component))
+		}
+	}
+
+	return ref, nil
+}
+
+// Validate returns an ErrInvalidReference if an image reference doesn't follow the Docker
+// grammar.
+func Validate(s string) error {
+	_, err := Parse(s)
+	return err
+}
+
+// isRegistry returns true if the first component of a name is a registry.
+func isRegistry(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost" ||
+		strings.ToLower(component) != component
+}
+
+// validateDigest returns why a digest is invalid, or an empty string if it's valid.
+func validateDigest(digest string) string {
+	if !digestPattern.MatchString(digest) {
+		return fmt.Sprintf("invalid digest %q", digest)
+	}
+
+	parts := strings.SplitN(digest, ":", 2)
+	if length, ok := digestLengths[parts[0]]; ok && len(parts[1]) != length {
+		return fmt.Sprintf("a %s digest must have %d hex characters", parts[0], length)
+	}
+	return ""
+}
+
+// Name returns the registry and repository of the reference, as given.
+func (r Reference) Name() string {
+	if r.Registry == "" {
+		return r.Repository
+	}
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the reference in the Docker format.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Normalized returns the reference with DefaultRegistry, the official repository namespace and
+// DefaultTag filled in where they're implied.
+func (r Reference) Normalized() Reference {
+	if r.Registry == "" {
+		r.Registry = DefaultRegistry
+	}
+	if r.Registry == DefaultRegistry && !strings.Contains(r.Repository, "/") {
+		r.Repository = officialRepository + "/" + r.Repository
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = DefaultTag
+	}
+	return r
+}
@@ -0,0 +1,279 @@
+package imageref
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+const digest = "sha256:8c4a7f3c1e2b9d0a5f6e7d8c9b0a1f2e3d4c5b6a7980f1e2d3c4b5a697887766"
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		s        string
+		expected Reference
+	}{
+		{"example-go", Reference{Repository: "example-go"}},
+		{"deis/example-go:v2", Reference{Repository: "deis/example-go", Tag: "v2"}},
+		{"quay.io/deis/example-go", Reference{Registry: "quay.io", Repository: "deis/example-go"}},
+		{"localhost:5000/example_go:1.0", Reference{Registry: "localhost:5000", Repository: "example_go", Tag: "1.0"}},
+		{"localhost/example-go", Reference{Registry: "localhost", Repository: "example-go"}},
+		{"deis/example-go@" + digest, Reference{Repository: "deis/example-go", Digest: digest}},
+		{"deis/example-go:v2@" + digest, Reference{Repository: "deis/example-go", Tag: "v2", Digest: digest}},
+	}
+
+	for _, check := range checks {
+		actual, err := Parse(check.s)
+		if err != nil {
+			t.Errorf("%s: %v", check.s, err)
+			continue
+		}
+		if actual != check.expected {
+			t.Errorf("Expected %v, Got %v", check.expected, actual)
+		}
+		if actual.String() != check.s {
+			t.Errorf("Expected %s, Got %s", check.s, actual.String())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		s      string
+		reason string
+	}{
+		{"", "the reference is empty"},
+		{"deis/Example-go", "the repository must be lowercase"},
+		{"deis/example-go:", `invalid tag ""`},
+		{"deis/example-go:-v2", `invalid tag "-v2"`},
+		{"deis//example-go", `invalid repository component ""`},
+		{"deis/example--go_", `invalid repository component "example--go_"`},
+		{"quay.io/", "the repository is empty"},
+		{"-quay.io/example-go", `invalid registry "-quay.io"`},
+		{"example-go@sha256:abc", `invalid digest "sha256:abc"`},
+		{"example-go@sha256:" + strings.Repeat("a", 40), "a sha256 digest must have 64 hex characters"},
+		{strings.Repeat("a", 256), "the name is longer than 255 characters"},
+	}
+
+	for _, check := range checks {
+		expected := ErrInvalidReference{Reference: check.s, Reason: check.reason}
+		if _, err := Parse(check.s); err != expected {
+			t.Errorf("Expected %v, Got %v", expected, err)
+		}
+	}
+}
+
+func TestNormalized(t *testing.T) {
+	t.Parallel()
+
+	checks := map[string]string{
+		"example-go":                  "docker.io/library/example-go:latest",
+		"deis/example-go:v2":          "docker.io/deis/example-go:v2",
+		"quay.io/example-go":          "quay.io/example-go:latest",
+		"example-go@" + digest:        "docker.io/library/example-go@" + digest,
+		"localhost:5000/deis/example": "localhost:5000/deis/example:latest",
+	}
+
+	for s, expected := range checks {
+		ref, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := ref.Normalized().String(); actual != expected {
+			t.Errorf("Expected %s, Got %s", expected, actual)
+		}
+	}
+}
+
+// fakeRegistry is a registry stand-in serving manifests behind token authentication.
+// HEAD requests get no digest header when headless is set, like some registries.
+type fakeRegistry struct {
+	mu       sync.Mutex
+	url      string
+	headless bool
+	requests []string
+}
+
+func (f *fakeRegistry) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req.Method+" "+req.URL.String())
+
+	if req.URL.Path == "/token" {
+		username, password, ok := req.BasicAuth()
+		if !ok || username != "deis" || password != "secret" {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Query().Get("scope") != "repository:deis/example-go:pull" {
+			res.WriteHeader(http.StatusForbidden)
+			return
+		}
+		res.Write([]byte(`{"token": "abc"}`))
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer abc" {
+		res.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:deis/example-go:pull"`, f.url))
+		res.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !strings.Contains(req.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.v2+json") {
+		res.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+
+	switch req.URL.Path {
+	case "/v2/deis/example-go/manifests/v2":
+		if req.Method == "GET" || !f.headless {
+			res.Header().Set("Docker-Content-Digest", digest)
+		}
+		res.Write([]byte(`{"schemaVersion": 2}`))
+	case "/v2/deis/example-go/manifests/unlabelled":
+		res.Write([]byte(`{"schemaVersion": 2}`))
+	default:
+		res.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeRegistry(headless bool) (*fakeRegistry, *httptest.Server) {
+	handler := &fakeRegistry{headless: headless}
+	server := httptest.NewServer(handler)
+	handler.url = server.URL
+	return handler, server
+}
+
+func TestPin(t *testing.T) {
+	t.Parallel()
+
+	handler, server := newFakeRegistry(false)
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "http://")
+	resolver := Resolver{Credentials: api.RegistryCredentials{Username: "deis", Password: "secret"}, Insecure: true}
+
+	ref, err := resolver.Pin(context.Background(), registry+"/deis/example-go:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := registry + "/deis/example-go:v2@" + digest
+	if ref.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, ref.String())
+	}
+
+	expectedRequests := []string{
+		"HEAD /v2/deis/example-go/manifests/v2",
+		"GET /token?scope=repository%3Adeis%2Fexample-go%3Apull&service=registry",
+		"HEAD /v2/deis/example-go/manifests/v2",
+	}
+	if strings.Join(handler.requests, "\n") != strings.Join(expectedRequests, "\n") {
+		t.Errorf("Expected %v, Got %v", expectedRequests, handler.requests)
+	}
+
+	// References with a digest are already pinned.
+	handler.requests = nil
+	if _, err := resolver.Pin(context.Background(), registry+"/deis/example-go@"+digest); err != nil {
+		t.Fatal(err)
+	}
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no requests, Got %v", handler.requests)
+	}
+}
+
+func TestPinWithoutDigestHeader(t *testing.T) {
+	t.Parallel()
+
+	_, server := newFakeRegistry(true)
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "http://")
+	resolver := Resolver{Credentials: api.RegistryCredentials{Username: "deis", Password: "secret"}, Insecure: true}
+
+	ref, err := resolver.Pin(context.Background(), registry+"/deis/example-go:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Digest != digest {
+		t.Errorf("Expected %s, Got %s", digest, ref.Digest)
+	}
+
+	// Without any digest header, the digest is the hash of the manifest.
+	ref, err = resolver.Pin(context.Background(), registry+"/deis/example-go:unlabelled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "sha256:c5d902c53b4afcf32ad746fd9d696431650d3fbe8f7b10ca10519543fefd772c"
+	if ref.Digest != expected {
+		t.Errorf("Expected %s, Got %s", expected, ref.Digest)
+	}
+}
+
+func TestPinErrors(t *testing.T) {
+	t.Parallel()
+
+	_, server := newFakeRegistry(false)
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	resolver := Resolver{Credentials: api.RegistryCredentials{Username: "deis", Password: "wrong"}, Insecure: true}
+	_, err := resolver.Pin(context.Background(), registry+"/deis/example-go:v2")
+	expected := ErrRegistry{Reference: registry + "/deis/example-go:v2", StatusCode: http.StatusUnauthorized}
+	if err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+	if strings.Contains(err.Error(), "wrong") {
+		t.Errorf("Expected the password to be left out of %v", err)
+	}
+
+	resolver.Credentials.Password = "secret"
+	_, err = resolver.Pin(context.Background(), registry+"/deis/example-go:v3")
+	expected = ErrRegistry{Reference: registry + "/deis/example-go:v3", StatusCode: http.StatusNotFound}
+	if err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+
+	if _, err := resolver.Pin(context.Background(), "deis/Example-go"); err == nil {
+		t.Error("Expected an invalid reference to fail")
+	}
+}
+
+func TestForApp(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+		res.Write([]byte(`{"registry": {"username": "deis", "password": "secret"}}`))
+	}))
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := ForApp(deis, "example-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := api.RegistryCredentials{Username: "deis", Password: "secret"}
+	if resolver.Credentials != expected {
+		t.Errorf("Expected %v, Got %v", expected, resolver.Credentials)
+	}
+}
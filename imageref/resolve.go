@@ -0,0 +1,285 @@
+package imageref
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+)
+
+// dockerHubHost is the API host of DefaultRegistry.
+const dockerHubHost = "registry-1.docker.io"
+
+// manifestTypes are the manifest media types accepted from a registry. Listing them makes
+// registries return the digest of the manifest the Docker daemon would pull.
+var manifestTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ErrNoDigest is returned when a registry's response doesn't identify a manifest digest.
+var ErrNoDigest = errors.New("The registry did not return a manifest digest")
+
+// ErrRegistry is returned when a registry responds to a manifest request with an error.
+type ErrRegistry struct {
+	Reference  string
+	StatusCode int
+}
+
+func (e ErrRegistry) Error() string {
+	if e.StatusCode == http.StatusNotFound {
+		return fmt.Sprintf("The image %s was not found in its registry", e.Reference)
+	}
+	return fmt.Sprintf("The registry returned %d %s for %s", e.StatusCode,
+		http.StatusText(e.StatusCode), e.Reference)
+}
+
+// Resolver pins image tags to their digests using a registry's v2 API.
+type Resolver struct {
+	// HTTPClient makes the registry requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+	// Credentials are sent to registries which require authentication.
+	Credentials api.RegistryCredentials
+	// Insecure uses plain HTTP instead of HTTPS, for local registries.
+	Insecure bool
+}
+
+// ForApp returns a Resolver using an app's registry credentials.
+func ForApp(c *deis.Client, appID string) (Resolver, error) {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Resolver{}, err
+	}
+	return Resolver{Credentials: cfg.RegistryCredentials()}, nil
+}
+
+// Pin parses an image reference and returns it with the digest of its tag. References which
+// already have a digest are returned without querying the registry.
+//
+// This example deploys the image currently tagged v2, even if the tag is later moved:
+//
+//    resolver, err := imageref.ForApp(client, "example-go")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    ref, err := resolver.Pin(context.Background(), "quay.io/deis/example-go:v2")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    _, err = builds.New(client, "example-go", ref.String(), nil)
+func (r Resolver) Pin(ctx context.Context, image string) (Reference, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return Reference{}, err
+	}
+	if ref.Digest != "" {
+		return ref, nil
+	}
+
+	if ref.Digest, err = r.Resolve(ctx, ref); err != nil {
+		return Reference{}, err
+	}
+	return ref, nil
+}
+
+// Resolve returns the digest of the manifest a reference points to.
+func (r Resolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	normal := ref.Normalized()
+	u := fmt.Sprintf("%s/v2/%s/manifests/%s", r.base(normal.Registry), normal.Repository, normal.Tag)
+
+	res, err := r.manifest(ctx, "HEAD", u, normal.Repository)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", ErrRegistry{Reference: ref.String(), StatusCode: res.StatusCode}
+	}
+
+	digest := res.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		// Some registries only return the digest header for GET requests, and some never do.
+		// The digest is then the hash of the manifest.
+		if digest, err = r.hashManifest(ctx, u, ref, normal.Repository); err != nil {
+			return "", err
+		}
+	}
+
+	if validateDigest(digest) != "" {
+		return "", ErrNoDigest
+	}
+	return digest, nil
+}
+
+func (r Resolver) hashManifest(ctx context.Context, u string, ref Reference, repository string) (string, error) {
+	res, err := r.manifest(ctx, "GET", u, repository)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", ErrRegistry{Reference: ref.String(), StatusCode: res.StatusCode}
+	}
+	if digest := res.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, res.Body); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// manifest requests a manifest, authenticating if the registry challenges the request.
+func (r Resolver) manifest(ctx context.Context, method, u, repository string) (*http.Response, error) {
+	res, err := r.do(ctx, method, u, "")
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	res.Body.Close()
+
+	authorization, err := r.authorize(ctx, res.Header.Get("WWW-Authenticate"), repository)
+	if err != nil {
+		return nil, err
+	}
+	if authorization == "" {
+		return res, nil
+	}
+	return r.do(ctx, method, u, authorization)
+}
+
+// authorize answers a registry's authentication challenge, returning the Authorization header
+// to retry with. An empty header means the challenge can't be answered.
+func (r Resolver) authorize(ctx context.Context, challenge, repository string) (string, error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+
+	switch scheme {
+	case "basic":
+		if r.Credentials.Empty() {
+			return "", nil
+		}
+		return "Basic " + r.basic(), nil
+	case "bearer":
+		return r.token(ctx, challenge, repository)
+	default:
+		return "", nil
+	}
+}
+
+// token fetches a bearer token from the token server named in a challenge.
+func (r Resolver) token(ctx context.Context, challenge, repository string) (string, error) {
+	params := map[string]string{}
+	for _, match := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", nil
+	}
+
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	authorization := ""
+	if !r.Credentials.Empty() {
+		authorization = "Basic " + r.basic()
+	}
+
+	res, err := r.do(ctx, "GET", realm.String(), authorization)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", nil
+	}
+	return "Bearer " + token.Token, nil
+}
+
+func (r Resolver) basic() string {
+	return base64.StdEncoding.EncodeToString([]byte(r.Credentials.Username + ":" + r.Credentials.Password))
+}
+
+func (r Resolver) do(ctx context.Context, method, u, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// base returns the URL of a registry's API.
+func (r Resolver) base(registry string) string {
+	if registry == DefaultRegistry {
+		registry = dockerHubHost
+	}
+	if r.Insecure {
+		return "http://" + registry
+	}
+	return "https://" + registry
+}
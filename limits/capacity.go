@@ -0,0 +1,184 @@
+package limits
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/ps"
+)
+
+const listLimit = 1000
+
+// Usage is the capacity reserved by the processes of a type.
+type Usage struct {
+	Type     string
+	Replicas int
+	// Memory and CPU are the limits of a single process. They're nil if the type has no limit.
+	Memory *MemoryLimit
+	CPU    *CPULimit
+}
+
+// Unlimited returns true if the processes can use unlimited memory or CPU.
+func (u Usage) Unlimited() bool {
+	return u.Memory == nil || u.CPU == nil
+}
+
+// Capacity is the memory and CPU reserved by an app's processes.
+type Capacity struct {
+	App   string
+	Types []Usage
+}
+
+// Memory returns the total memory limit of the app's limited processes.
+func (c Capacity) Memory() MemoryLimit {
+	var total MemoryLimit
+	for _, u := range c.Types {
+		if u.Memory != nil {
+			total = total.add(u.Memory.Times(u.Replicas))
+		}
+	}
+	return total
+}
+
+// CPU returns the total CPU limit of the app's limited processes.
+func (c Capacity) CPU() CPULimit {
+	var total CPULimit
+	for _, u := range c.Types {
+		if u.CPU != nil {
+			total = total.add(u.CPU.Times(u.Replicas))
+		}
+	}
+	return total
+}
+
+// Unlimited returns the running process types without a memory or CPU limit.
+func (c Capacity) Unlimited() []string {
+	var types []string
+	for _, u := range c.Types {
+		if u.Replicas > 0 && u.Unlimited() {
+			types = append(types, u.Type)
+		}
+	}
+	return types
+}
+
+// String renders the Capacity in a readable format.
+func (c Capacity) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== %s capacity\n", c.App)
+	for _, u := range c.Types {
+		fmt.Fprintf(&b, "%s: %d x %s memory, %s cpu\n", u.Type, u.Replicas, memoryString(u.Memory), cpuString(u.CPU))
+	}
+	fmt.Fprintf(&b, "total: %s memory, %s cpu\n", c.Memory(), c.CPU())
+	if unlimited := c.Unlimited(); len(unlimited) > 0 {
+		fmt.Fprintf(&b, "unlimited: %v\n", unlimited)
+	}
+	return b.String()
+}
+
+// Report multiplies an app's limits by the number of processes of each type, counted with
+// ps.Replicas. Process types with limits but no running processes are included with no
+// replicas.
+func Report(c *deis.Client, appID string) (Capacity, error) {
+	l, err := Get(c, appID)
+	if err != nil {
+		return Capacity{}, err
+	}
+
+	pods, _, err := ps.List(c, appID, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Capacity{}, err
+	}
+
+	replicas := ps.Replicas(pods)
+
+	types := map[string]bool{}
+	for procType := range replicas {
+		types[procType] = true
+	}
+	for procType := range l.Memory {
+		types[procType] = true
+	}
+	for procType := range l.CPU {
+		types[procType] = true
+	}
+
+	var sorted []string
+	for procType := range types {
+		sorted = append(sorted, procType)
+	}
+	sort.Strings(sorted)
+
+	capacity := Capacity{App: appID}
+	for _, procType := range sorted {
+		u := Usage{Type: procType, Replicas: replicas[procType]}
+		if limit, ok := l.Memory[procType]; ok {
+			u.Memory = &limit
+		}
+		if limit, ok := l.CPU[procType]; ok {
+			u.CPU = &limit
+		}
+		capacity.Types = append(capacity.Types, u)
+	}
+
+	return capacity, nil
+}
+
+// Total adds up the capacity of several apps.
+func Total(capacities ...Capacity) (MemoryLimit, CPULimit) {
+	var memory MemoryLimit
+	var cpu CPULimit
+	for _, c := range capacities {
+		memory = memory.add(c.Memory())
+		cpu = cpu.add(c.CPU())
+	}
+	return memory, cpu
+}
+
+// add adds two memory limits. A limit without a request requests its limit, so the sum only
+// has a request if one of them does.
+func (l MemoryLimit) add(o MemoryLimit) MemoryLimit {
+	if l.Request == 0 && o.Request == 0 {
+		return MemoryLimit{Limit: l.Limit + o.Limit}
+	}
+	return MemoryLimit{Request: l.request() + o.request(), Limit: l.Limit + o.Limit}
+}
+
+func (l MemoryLimit) request() Memory {
+	if l.Request == 0 {
+		return l.Limit
+	}
+	return l.Request
+}
+
+// add adds two CPU limits. A limit without a request requests its limit, so the sum only has
+// a request if one of them does.
+func (l CPULimit) add(o CPULimit) CPULimit {
+	if l.Request == 0 && o.Request == 0 {
+		return CPULimit{Limit: l.Limit + o.Limit}
+	}
+	return CPULimit{Request: l.request() + o.request(), Limit: l.Limit + o.Limit}
+}
+
+func (l CPULimit) request() CPU {
+	if l.Request == 0 {
+		return l.Limit
+	}
+	return l.Request
+}
+
+func memoryString(l *MemoryLimit) string {
+	if l == nil {
+		return "unlimited"
+	}
+	return l.String()
+}
+
+func cpuString(l *CPULimit) string {
+	if l == nil {
+		return "unlimited"
+	}
+	return l.String()
+}
@@ -0,0 +1,242 @@
+// Package limits provides methods for reading and setting an app's memory and CPU limits as
+// typed quantities.
+//
+// Limits are set per process type, either as a single limit or as a request and a limit, such
+// as "256Mi/512Mi". Quantities are parsed like Kubernetes quantities: memory suffixes k, M, G
+// and T are decimal, and Ki, Mi, Gi and Ti are binary. CPU is set in cores, such as "1" or
+// "0.5", or in millicores, such as "250m".
+//
+// The controller has its own memory format, where "512M" is binary. Limits are written in that
+// format, and read from it by FromConfig and Get, so a limit parsed from "500M" is set as
+// "500000000B", the same number of bytes.
+//
+// This example sets web's memory limit and CPU request and limit:
+//
+//    l := limits.Limits{
+//        Memory: map[string]limits.MemoryLimit{"web": {Limit: 512 * limits.Mebibyte}},
+//        CPU:    map[string]limits.CPULimit{"web": {Request: 250, Limit: limits.Core}},
+//    }
+//    _, err := limits.Set(client, "example-go", l)
+package limits
+
+import (
+	"fmt"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+)
+
+// ErrRequestAboveLimit is returned when a process type requests more of a resource than its
+// limit.
+type ErrRequestAboveLimit struct {
+	// Resource is "memory" or "cpu".
+	Resource string
+	Type     string
+	Value    string
+}
+
+func (e ErrRequestAboveLimit) Error() string {
+	return fmt.Sprintf("The %s request of %s is above its limit in %q", e.Resource, e.Type, e.Value)
+}
+
+// MemoryLimit is a memory limit with an optional request. A zero Request means only the limit
+// is set.
+type MemoryLimit struct {
+	Request Memory
+	Limit   Memory
+}
+
+// ParseMemoryLimit parses a memory limit of Kubernetes quantities, such as "512Mi", or a
+// request and a limit, such as "256Mi/512Mi".
+func ParseMemoryLimit(s string) (MemoryLimit, error) {
+	return parseMemoryLimit(s, ParseMemory)
+}
+
+// ParseControllerMemoryLimit parses a memory limit in the controller's format, such as "512M"
+// or "256M/512M". It accepts exactly the limits config.Validate does.
+func ParseControllerMemoryLimit(s string) (MemoryLimit, error) {
+	if !config.ValidMemoryLimit(s) {
+		return MemoryLimit{}, ErrInvalidQuantity{Resource: "memory", Value: s, Reason: "expected a whole number followed by B, K, M or G"}
+	}
+	return parseMemoryLimit(s, ParseControllerMemory)
+}
+
+func parseMemoryLimit(s string, parse func(string) (Memory, error)) (MemoryLimit, error) {
+	request, limit := split(s)
+
+	var l MemoryLimit
+	var err error
+	if l.Limit, err = parse(limit); err != nil {
+		return MemoryLimit{}, err
+	}
+	if request != "" {
+		if l.Request, err = parse(request); err != nil {
+			return MemoryLimit{}, err
+		}
+	}
+	return l, nil
+}
+
+// Validate returns an error if the request is above the limit.
+func (l MemoryLimit) Validate(procType string) error {
+	if l.Request > l.Limit {
+		return ErrRequestAboveLimit{Resource: "memory", Type: procType, Value: l.String()}
+	}
+	return nil
+}
+
+// Times returns the limit of n processes.
+func (l MemoryLimit) Times(n int) MemoryLimit {
+	return MemoryLimit{Request: l.Request * Memory(n), Limit: l.Limit * Memory(n)}
+}
+
+// String formats the limit in the controller's format, such as "512M" or "256M/512M".
+func (l MemoryLimit) String() string {
+	if l.Request == 0 {
+		return l.Limit.String()
+	}
+	return l.Request.String() + "/" + l.Limit.String()
+}
+
+// CPULimit is a CPU limit with an optional request. A zero Request means only the limit is set.
+type CPULimit struct {
+	Request CPU
+	Limit   CPU
+}
+
+// ParseCPULimit parses a CPU limit, such as "1", or a request and a limit, such as "250m/1".
+func ParseCPULimit(s string) (CPULimit, error) {
+	request, limit := split(s)
+
+	var l CPULimit
+	var err error
+	if l.Limit, err = ParseCPU(limit); err != nil {
+		return CPULimit{}, err
+	}
+	if request != "" {
+		if l.Request, err = ParseCPU(request); err != nil {
+			return CPULimit{}, err
+		}
+	}
+	return l, nil
+}
+
+// Validate returns an error if the request is above the limit.
+func (l CPULimit) Validate(procType string) error {
+	if l.Request > l.Limit {
+		return ErrRequestAboveLimit{Resource: "cpu", Type: procType, Value: l.String()}
+	}
+	return nil
+}
+
+// Times returns the limit of n processes.
+func (l CPULimit) Times(n int) CPULimit {
+	return CPULimit{Request: l.Request * CPU(n), Limit: l.Limit * CPU(n)}
+}
+
+// String formats the limit in the controller's format, such as "1" or "250m/1".
+func (l CPULimit) String() string {
+	if l.Request == 0 {
+		return l.Limit.String()
+	}
+	return l.Request.String() + "/" + l.Limit.String()
+}
+
+// Limits are an app's memory and CPU limits by process type.
+type Limits struct {
+	Memory map[string]MemoryLimit
+	CPU    map[string]CPULimit
+}
+
+// Validate returns an error if a process type requests more than its limit.
+func (l Limits) Validate() error {
+	for procType, limit := range l.Memory {
+		if err := limit.Validate(procType); err != nil {
+			return err
+		}
+	}
+	for procType, limit := range l.CPU {
+		if err := limit.Validate(procType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromConfig parses the limits of an app's config, which are in the controller's format.
+func FromConfig(cfg api.Config) (Limits, error) {
+	l := Limits{Memory: map[string]MemoryLimit{}, CPU: map[string]CPULimit{}}
+
+	for procType, value := range cfg.Memory {
+		limit, err := ParseControllerMemoryLimit(fmt.Sprint(value))
+		if err != nil {
+			return Limits{}, err
+		}
+		l.Memory[procType] = limit
+	}
+	for procType, value := range cfg.CPU {
+		limit, err := ParseCPULimit(fmt.Sprint(value))
+		if err != nil {
+			return Limits{}, err
+		}
+		l.CPU[procType] = limit
+	}
+
+	return l, nil
+}
+
+// Config returns the limits as a config patch, normalized to the controller's format.
+func (l Limits) Config() api.Config {
+	cfg := api.Config{}
+	if len(l.Memory) > 0 {
+		cfg.Memory = map[string]interface{}{}
+		for procType, limit := range l.Memory {
+			cfg.Memory[procType] = limit.String()
+		}
+	}
+	if len(l.CPU) > 0 {
+		cfg.CPU = map[string]interface{}{}
+		for procType, limit := range l.CPU {
+			cfg.CPU[procType] = limit.String()
+		}
+	}
+	return cfg
+}
+
+// Get retrieves an app's limits.
+func Get(c *deis.Client, appID string) (Limits, error) {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Limits{}, err
+	}
+	return FromConfig(cfg)
+}
+
+// Set sets an app's limits in a single release. Process types that aren't in l are left alone.
+// The limits are checked before anything is changed.
+func Set(c *deis.Client, appID string, l Limits) (api.Config, error) {
+	if err := l.Validate(); err != nil {
+		return api.Config{}, err
+	}
+
+	patch := l.Config()
+	if err := config.Validate(patch); err != nil {
+		return api.Config{}, err
+	}
+	if len(patch.Memory) == 0 && len(patch.CPU) == 0 {
+		return api.Config{}, config.ErrNoChanges
+	}
+
+	return config.Set(c, appID, patch)
+}
+
+// split splits "req/limit" into its request and limit. The request is empty if there isn't one.
+func split(s string) (string, string) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,318 @@
+package limits
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+)
+
+const podFixture string = `{"release": "v2", "type": "%s", "name": "%s-%d", "state": "up", "started": "2016-02-13T00:47:52"}`
+
+// fakeHTTPServer keeps the config and scale of an app, and records every config change.
+type fakeHTTPServer struct {
+	mu      sync.Mutex
+	config  api.Config
+	scale   map[string]int
+	patches []string
+}
+
+func newFakeHTTPServer() *fakeHTTPServer {
+	return &fakeHTTPServer{
+		config: api.Config{
+			App:    "example-go",
+			Memory: map[string]interface{}{"web": "256M/512M"},
+			CPU:    map[string]interface{}{"web": "250m/1", "clock": "500m"},
+		},
+		scale: map[string]int{"web": 3, "worker": 1},
+	}
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case req.URL.Path == "/v2/apps/example-go/config/" && req.Method == "GET":
+		out, _ := json.Marshal(f.config)
+		res.Write(out)
+	case req.URL.Path == "/v2/apps/example-go/config/" && req.Method == "POST":
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fmt.Println(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		f.patches = append(f.patches, string(body))
+
+		patch := api.Config{}
+		json.Unmarshal(body, &patch)
+		for procType, value := range patch.Memory {
+			f.config.Memory[procType] = value
+		}
+		for procType, value := range patch.CPU {
+			f.config.CPU[procType] = value
+		}
+		out, _ := json.Marshal(f.config)
+		res.WriteHeader(http.StatusCreated)
+		res.Write(out)
+	case req.URL.Path == "/v2/apps/example-go/pods/" && req.Method == "GET":
+		var pods []string
+		for procType, count := range f.scale {
+			for i := 0; i < count; i++ {
+				pods = append(pods, fmt.Sprintf(podFixture, procType, procType, i))
+			}
+		}
+		// A web pod that's shutting down isn't counted.
+		pods = append(pods, `{"release": "v2", "type": "web", "name": "web-9", "state": "terminating"}`)
+		res.Write([]byte(fmt.Sprintf(`{"count": %d, "results": [%s]}`, len(pods), strings.Join(pods, ","))))
+	default:
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		value    string
+		expected Memory
+		format   string
+	}{
+		{"512Mi", 512 * Mebibyte, "512M"},
+		{"1.5Gi", 1536 * Mebibyte, "1536M"},
+		{"64Ki", 64 * Kibibyte, "64K"},
+		{"1Ti", 1024 * Gibibyte, "1024G"},
+		{"500M", 500 * Megabyte, "500000000B"},
+		{"1.024k", 1024, "1K"},
+		{"2G", 2 * Gigabyte, "1953125K"},
+		{"1T", Terabyte, "976562500K"},
+		{"1000", 1000, "1000B"},
+		{"0", 0, "0"},
+	}
+
+	for _, check := range checks {
+		m, err := ParseMemory(check.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != check.expected {
+			t.Errorf("Expected %d for %q, Got %d", check.expected, check.value, m)
+		}
+		if m.String() != check.format {
+			t.Errorf("Expected %s, Got %s", check.format, m)
+		}
+	}
+
+	invalid := []string{
+		"", "M", "-1M", "512X", "512mb", "512m", "1.5", "0.1Ki", "1e3",
+		"9999999999G", "9223372036854775808", "8589934592Gi", "1.0000000000000000001Ki",
+	}
+	for _, value := range invalid {
+		if _, err := ParseMemory(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		} else if _, ok := err.(ErrInvalidQuantity); !ok {
+			t.Errorf("Expected ErrInvalidQuantity, Got %v", err)
+		}
+	}
+}
+
+func TestParseControllerMemory(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		value    string
+		expected Memory
+	}{
+		{"512M", 512 * Mebibyte},
+		{"512mb", 512 * Mebibyte},
+		{"1G", Gibibyte},
+		{"64k", 64 * Kibibyte},
+		{"100B", 100},
+		{"0", 0},
+	}
+
+	for _, check := range checks {
+		m, err := ParseControllerMemory(check.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != check.expected {
+			t.Errorf("Expected %d for %q, Got %d", check.expected, check.value, m)
+		}
+	}
+
+	for _, value := range []string{"512Mi", "1T", "1000", "99999999999G", "1.5G", " 512M", "256M/512M"} {
+		if _, err := ParseControllerMemory(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+
+	// The controller's format is the one config.Validate checks.
+	for _, value := range []string{"512M", "256M/512M", "0", "1.5G", "0/0", "1G/", "512Mi"} {
+		_, err := ParseControllerMemoryLimit(value)
+		if valid := config.ValidMemoryLimit(value); (err == nil) != valid {
+			t.Errorf("Expected %q to be valid: %v, Got %v", value, valid, err)
+		}
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		value    string
+		expected CPU
+		format   string
+	}{
+		{"1", Core, "1"},
+		{"0.5", 500, "500m"},
+		{".1", 100, "100m"},
+		{"250m", 250, "250m"},
+		{"2000m", 2 * Core, "2"},
+	}
+
+	for _, check := range checks {
+		cpu, err := ParseCPU(check.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cpu != check.expected {
+			t.Errorf("Expected %d, Got %d", check.expected, cpu)
+		}
+		if cpu.String() != check.format {
+			t.Errorf("Expected %s, Got %s", check.format, cpu)
+		}
+	}
+
+	for _, value := range []string{"", "-1", "0.0005", "1.5m", "250M", "one"} {
+		if _, err := ParseCPU(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	t.Parallel()
+
+	memory, err := ParseMemoryLimit("256Mi/0.5Gi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "256M/512M"; memory.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, memory)
+	}
+
+	cpu, err := ParseCPULimit("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (CPULimit{Limit: Core}); cpu != expected {
+		t.Errorf("Expected %v, Got %v", expected, cpu)
+	}
+
+	if _, err := ParseMemoryLimit("256M/"); err == nil {
+		t.Error("Expected an error for a missing limit")
+	}
+
+	memory, err = ParseControllerMemoryLimit("1G/512M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ErrRequestAboveLimit{Resource: "memory", Type: "web", Value: "1G/512M"}
+	if err := memory.Validate("web"); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Get(deis, "example-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Limits{
+		Memory: map[string]MemoryLimit{"web": {Request: 256 * Mebibyte, Limit: 512 * Mebibyte}},
+		CPU:    map[string]CPULimit{"web": {Request: 250, Limit: Core}, "clock": {Limit: 500}},
+	}
+	if !reflect.DeepEqual(expected, l) {
+		t.Errorf("Expected %v, Got %v", expected, l)
+	}
+
+	_, err = Set(deis, "example-go", Limits{Memory: map[string]MemoryLimit{"worker": {Limit: Gibibyte}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patches := []string{`{"memory":{"worker":"1G"}}`}
+	if !reflect.DeepEqual(patches, handler.patches) {
+		t.Errorf("Expected %v, Got %v", patches, handler.patches)
+	}
+
+	_, err = Set(deis, "example-go", Limits{CPU: map[string]CPULimit{"web": {Request: 2 * Core, Limit: Core}}})
+	if _, ok := err.(ErrRequestAboveLimit); !ok {
+		t.Errorf("Expected ErrRequestAboveLimit, Got %v", err)
+	}
+	if len(handler.patches) != 1 {
+		t.Errorf("Expected 1 patch, Got %v", handler.patches)
+	}
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(newFakeHTTPServer())
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capacity, err := Report(deis, "example-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `=== example-go capacity
+clock: 0 x unlimited memory, 500m cpu
+web: 3 x 256M/512M memory, 250m/1 cpu
+worker: 1 x unlimited memory, unlimited cpu
+total: 768M/1536M memory, 750m/3 cpu
+unlimited: [worker]
+`
+	if capacity.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, capacity)
+	}
+
+	memory, cpu := Total(capacity, capacity)
+	if expected := "1536M/3G"; memory.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, memory)
+	}
+	if expected := "1500m/6"; cpu.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, cpu)
+	}
+}
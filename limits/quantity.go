@@ -0,0 +1,255 @@
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/deis/controller-sdk-go/config"
+)
+
+// Memory is an amount of memory in bytes.
+type Memory int64
+
+// CPU is an amount of CPU in millicores, thousandths of a core.
+type CPU int64
+
+// Binary memory units.
+const (
+	Byte     Memory = 1
+	Kibibyte        = 1024 * Byte
+	Mebibyte        = 1024 * Kibibyte
+	Gibibyte        = 1024 * Mebibyte
+	Tebibyte        = 1024 * Gibibyte
+)
+
+// Decimal memory units.
+const (
+	Kilobyte Memory = 1000 * Byte
+	Megabyte        = 1000 * Kilobyte
+	Gigabyte        = 1000 * Megabyte
+	Terabyte        = 1000 * Gigabyte
+)
+
+// Core is one CPU core.
+const Core CPU = 1000
+
+// maxFractionDigits is the most digits after a decimal point that are parsed, so the
+// fraction's denominator fits in an int64.
+const maxFractionDigits = 18
+
+var (
+	memoryQuantity = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([A-Za-z]*)$`)
+	cpuQuantity    = regexp.MustCompile(`^([0-9]*\.?[0-9]+)(m?)$`)
+)
+
+// memoryUnits are the Kubernetes memory suffixes: decimal k, M, G and T, and binary Ki, Mi, Gi
+// and Ti.
+var memoryUnits = map[string]Memory{
+	"":   Byte,
+	"k":  Kilobyte,
+	"M":  Megabyte,
+	"G":  Gigabyte,
+	"T":  Terabyte,
+	"Ki": Kibibyte,
+	"Mi": Mebibyte,
+	"Gi": Gibibyte,
+	"Ti": Tebibyte,
+}
+
+// controllerUnits are the controller's memory suffixes, matched case-insensitively. The
+// controller treats them all as binary units.
+var controllerUnits = map[string]Memory{
+	"b":  Byte,
+	"k":  Kibibyte,
+	"kb": Kibibyte,
+	"m":  Mebibyte,
+	"mb": Mebibyte,
+	"g":  Gibibyte,
+	"gb": Gibibyte,
+}
+
+var (
+	errOverflow  = errors.New("too large")
+	errPrecision = errors.New("more than 18 digits after the decimal point")
+)
+
+// ErrInvalidQuantity is returned when a memory or CPU quantity can't be parsed.
+type ErrInvalidQuantity struct {
+	// Resource is "memory" or "cpu".
+	Resource string
+	Value    string
+	Reason   string
+}
+
+func (e ErrInvalidQuantity) Error() string {
+	return fmt.Sprintf("Invalid %s quantity %q: %s", e.Resource, e.Value, e.Reason)
+}
+
+// ParseMemory parses a Kubernetes memory quantity, such as "512Mi", "1.5Gi" or "500M". The
+// suffixes k, M, G and T are decimal, powers of 1000, and Ki, Mi, Gi and Ti are binary, powers
+// of 1024. A plain number is bytes.
+//
+// This is not the controller's format, where "512M" is binary. Use ParseControllerMemory to
+// read values from an app's config.
+func ParseMemory(s string) (Memory, error) {
+	return parseMemory(s, func(suffix string) (Memory, bool) {
+		unit, ok := memoryUnits[suffix]
+		return unit, ok
+	})
+}
+
+// ParseControllerMemory parses a memory quantity in the controller's format, such as "512M",
+// "1G" or "64kb". The suffixes B, K, M and G, with an optional B, are matched in any case and
+// are binary, so "512M" is 512 mebibytes. A plain "0" is no memory. The format is the one
+// config.Validate accepts, so fractions such as "1.5G" aren't allowed.
+func ParseControllerMemory(s string) (Memory, error) {
+	if strings.Contains(s, "/") || !config.ValidMemoryLimit(s) {
+		return 0, ErrInvalidQuantity{Resource: "memory", Value: s, Reason: "expected a whole number followed by B, K, M or G"}
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	return parseMemory(s, func(suffix string) (Memory, bool) {
+		unit, ok := controllerUnits[strings.ToLower(suffix)]
+		return unit, ok
+	})
+}
+
+func parseMemory(s string, units func(suffix string) (Memory, bool)) (Memory, error) {
+	invalid := func(reason string) (Memory, error) {
+		return 0, ErrInvalidQuantity{Resource: "memory", Value: s, Reason: reason}
+	}
+
+	match := memoryQuantity.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return invalid("expected a number followed by a unit")
+	}
+
+	unit, ok := units(match[2])
+	if !ok {
+		return invalid(fmt.Sprintf("unknown unit %q", match[2]))
+	}
+
+	bytes, whole, err := scale(match[1], int64(unit))
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if !whole {
+		return invalid("not a whole number of bytes")
+	}
+	return Memory(bytes), nil
+}
+
+// String formats the memory in the controller's format, using the largest binary unit that
+// divides it, such as "512M" or "2G".
+func (m Memory) String() string {
+	units := []struct {
+		suffix string
+		size   Memory
+	}{{"G", Gibibyte}, {"M", Mebibyte}, {"K", Kibibyte}}
+
+	if m == 0 {
+		return "0"
+	}
+	for _, unit := range units {
+		if m%unit.size == 0 {
+			return fmt.Sprintf("%d%s", m/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", m)
+}
+
+// ParseCPU parses a CPU quantity in cores, such as "2" or "0.5", or in millicores, such as
+// "250m". This is the same in Kubernetes and the controller.
+func ParseCPU(s string) (CPU, error) {
+	invalid := func(reason string) (CPU, error) {
+		return 0, ErrInvalidQuantity{Resource: "cpu", Value: s, Reason: reason}
+	}
+
+	match := cpuQuantity.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return invalid("expected a number of cores, or of millicores followed by m")
+	}
+
+	unit := int64(Core)
+	if match[2] == "m" {
+		unit = 1
+	}
+
+	millicores, whole, err := scale(match[1], unit)
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if !whole {
+		return invalid("more precise than a millicore")
+	}
+	return CPU(millicores), nil
+}
+
+// String formats the CPU in the controller's format, in cores if whole, such as "2", and in
+// millicores otherwise, such as "250m".
+func (c CPU) String() string {
+	if c%Core == 0 {
+		return strconv.FormatInt(int64(c/Core), 10)
+	}
+	return fmt.Sprintf("%dm", int64(c))
+}
+
+// scale multiplies a decimal number by unit, and reports whether the result is whole. It avoids
+// floating point, so "0.1" cores is exactly 100 millicores, and returns errOverflow rather than
+// wrapping around.
+func scale(number string, unit int64) (int64, bool, error) {
+	parts := strings.SplitN(number, ".", 2)
+	if parts[0] == "" {
+		parts[0] = "0"
+	}
+
+	whole, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || whole > math.MaxInt64/unit {
+		return 0, false, errOverflow
+	}
+	result := whole * unit
+	if len(parts) == 1 {
+		return result, true, nil
+	}
+
+	digits := strings.TrimRight(parts[1], "0")
+	if len(digits) > maxFractionDigits {
+		return 0, false, errPrecision
+	}
+	if digits == "" {
+		return result, true, nil
+	}
+
+	fraction, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, false, errPrecision
+	}
+	denominator := int64(1)
+	for range digits {
+		denominator *= 10
+	}
+
+	// fraction * unit / denominator, reduced first so it can't overflow. The fraction is below
+	// the denominator, so the result is below unit.
+	g := gcd(unit, denominator)
+	if fraction%(denominator/g) != 0 {
+		return 0, false, nil
+	}
+	part := fraction / (denominator / g) * (unit / g)
+	if result > math.MaxInt64-part {
+		return 0, false, errOverflow
+	}
+	return result + part, true, nil
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
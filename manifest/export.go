@@ -0,0 +1,201 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/sealed"
+	"github.com/deis/controller-sdk-go/sensitive"
+	"github.com/ghodss/yaml"
+)
+
+// Redacted replaces secret config values in manifests exported with SecretsRedact.
+// Plan leaves redacted variables unchanged.
+const Redacted = "<redacted>"
+
+var (
+	// ErrRedacted is returned when planning a manifest with a redacted value for a config
+	// variable that is not set on the app, as the value cannot be restored.
+	ErrRedacted = errors.New("The manifest contains a redacted value for a config variable that is not set")
+	// ErrEncrypted is returned when planning a manifest that contains values sealed by Export.
+	// The manifest must be decrypted with Decrypt first.
+	ErrEncrypted = errors.New("The manifest contains encrypted values and must be decrypted first")
+	// ErrMissingCipher is returned when encrypting or decrypting without a cipher.
+	ErrMissingCipher = errors.New("A cipher is required to encrypt or decrypt secrets")
+)
+
+// Format is an encoding of a manifest.
+type Format int
+
+const (
+	// YAML encodes manifests as YAML.
+	YAML Format = iota
+	// JSON encodes manifests as indented JSON.
+	JSON
+)
+
+// Secrets determines how secret config values are written when exporting an app.
+type Secrets int
+
+const (
+	// SecretsPlain exports secret values as is.
+	SecretsPlain Secrets = iota
+	// SecretsRedact replaces secret values with Redacted.
+	SecretsRedact
+	// SecretsEncrypt seals secret values with the export's Cipher.
+	SecretsEncrypt
+)
+
+// Cipher seals and opens secret config values. The config key is passed with its value, so a
+// sealed value can't be moved to another key. A *sealed.Keyring is a Cipher, so exported values
+// are in the same format as values set with sealed.Set, and can be rotated.
+type Cipher interface {
+	Seal(key, value string) (string, error)
+	Open(key, value string) (string, error)
+}
+
+// ExportOptions controls how an app is exported.
+type ExportOptions struct {
+	// Secrets determines how secret config values are written.
+	Secrets Secrets
+	// IsSecret reports whether a config variable holds a secret. If it is nil, Classifier
+	// decides.
+	IsSecret func(key string) bool
+	// Classifier decides which config values are secret when IsSecret is nil.
+	Classifier sensitive.Classifier
+	// Cipher seals secret values when Secrets is SecretsEncrypt.
+	Cipher Cipher
+}
+
+// Export retrieves the live state of every section of an app as a manifest.
+// Applying the manifest to the same app results in an empty changeset. The build is the one
+// the latest release runs, so after a rollback the manifest records the build rolled back to
+// rather than the newest one.
+func Export(c *deis.Client, app string, opts ExportOptions) (Manifest, error) {
+	a, err := apps.Get(c, app)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Manifest{}, err
+	}
+
+	enforced := false
+	m, err := fetch(c, Manifest{
+		App:           app,
+		Config:        map[string]string{},
+		Settings:      &Settings{},
+		Whitelist:     []string{},
+		Domains:       []string{},
+		Certs:         map[string][]string{},
+		TLS:           &enforced,
+		Collaborators: []string{},
+		Build:         &Build{},
+		Scale:         map[string]int{},
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+	m.Owner = a.Owner
+
+	if opts.Secrets == SecretsPlain {
+		return m, nil
+	}
+
+	if opts.Secrets == SecretsEncrypt && opts.Cipher == nil {
+		return Manifest{}, ErrMissingCipher
+	}
+
+	for key, value := range m.Config {
+		if opts.IsSecret != nil && !opts.IsSecret(key) {
+			continue
+		}
+		if opts.IsSecret == nil && !opts.Classifier.Sensitive(key, value) {
+			continue
+		}
+		if opts.Secrets == SecretsRedact {
+			m.Config[key] = Redacted
+			continue
+		}
+		// Values set with sealed.Set are already sealed, and are exported as they are.
+		if sealed.IsSealed(value) {
+			continue
+		}
+		if m.Config[key], err = opts.Cipher.Seal(key, value); err != nil {
+			return Manifest{}, err
+		}
+		m.Sealed = append(m.Sealed, key)
+	}
+	sort.Strings(m.Sealed)
+
+	return m, nil
+}
+
+// Marshal encodes a manifest in the given format.
+func Marshal(m Manifest, format Format) ([]byte, error) {
+	if format == YAML {
+		return yaml.Marshal(m)
+	}
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// Decrypt opens the config values of a manifest that Export sealed with SecretsEncrypt. Other
+// sealed values, such as those set with sealed.Set, are left sealed, as they are in the app.
+func Decrypt(m Manifest, c Cipher) (Manifest, error) {
+	if c == nil {
+		return Manifest{}, ErrMissingCipher
+	}
+	if len(m.Sealed) == 0 {
+		return m, nil
+	}
+
+	values := map[string]string{}
+	for key, value := range m.Config {
+		values[key] = value
+	}
+	for _, key := range m.Sealed {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		plaintext, err := c.Open(key, value)
+		if err != nil {
+			return Manifest{}, err
+		}
+		values[key] = plaintext
+	}
+
+	m.Config = values
+	m.Sealed = nil
+	return m, nil
+}
+
+// checkSecrets ensures that the redacted and sealed values in a manifest can be planned.
+func checkSecrets(live, desired Manifest) error {
+	if len(desired.Sealed) > 0 {
+		return ErrEncrypted
+	}
+	for key, value := range desired.Config {
+		if _, ok := live.Config[key]; value == Redacted && !ok {
+			return ErrRedacted
+		}
+	}
+	return nil
+}
+
+// unredact replaces the redacted values of a manifest with their live values.
+func unredact(live, desired Manifest) Manifest {
+	if desired.Config == nil {
+		return desired
+	}
+
+	values := map[string]string{}
+	for key, value := range desired.Config {
+		if value == Redacted {
+			value = live.Config[key]
+		}
+		values[key] = value
+	}
+	desired.Config = values
+	return desired
+}
@@ -0,0 +1,131 @@
+// Package manifest provides methods for declaratively managing apps with manifest files.
+//
+// A manifest describes the desired state of an app in YAML or JSON. Plan compares a manifest
+// to the live app and Apply makes the minimal set of SDK calls to bring the app in line with it.
+//
+// Sections which are omitted from a manifest (or set to null) are left unmanaged. Sections which
+// are present are authoritative, so anything in the live app that is missing from the section
+// will be removed. The exception is scale, where process types that are not listed are left alone.
+//
+// Export writes the live state of an app as a manifest, optionally redacting secret config
+// values or sealing them with a sealed.Keyring, which can be applied to the same or another app.
+//
+// This example manifest manages an app's config, domains and scale:
+//
+//    version: 1
+//    app: example-go
+//    config:
+//      DATABASE_URL: postgres://db.example.com/example
+//    domains:
+//      - example.com
+//    scale:
+//      web: 2
+package manifest
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/ghodss/yaml"
+)
+
+// Version is the manifest format version understood by this package.
+const Version = 1
+
+var (
+	// ErrMissingApp is returned when a manifest does not name an app.
+	ErrMissingApp = errors.New("The manifest does not specify an app")
+	// ErrUnsupportedVersion is returned when a manifest uses an unknown format version.
+	ErrUnsupportedVersion = errors.New("The manifest version is not supported")
+)
+
+// Manifest is the desired state of an app.
+type Manifest struct {
+	// Version is the manifest format version. If it is zero, the current version is assumed.
+	Version int `json:"version"`
+	// App is the name of the app the manifest describes.
+	App string `json:"app"`
+	// Owner is the app owner. It is recorded by Export and is not changed by Apply.
+	Owner string `json:"owner,omitempty"`
+	// Sealed are the config keys whose values Export sealed. Decrypt opens them, and Plan
+	// refuses a manifest with sealed keys until it has.
+	Sealed []string `json:"sealed,omitempty"`
+	// Config are the environment variables set on the app.
+	Config map[string]string `json:"config"`
+	// Memory are the memory limits of each process type.
+	Memory map[string]string `json:"memory"`
+	// CPU are the CPU limits of each process type.
+	CPU map[string]string `json:"cpu"`
+	// Healthchecks are the healthchecks of each process type.
+	Healthchecks map[string]*api.Healthchecks `json:"healthchecks"`
+	// Tags restrict the app to run on k8s nodes with the given labels.
+	Tags map[string]string `json:"tags"`
+	// Settings are the app's settings.
+	Settings *Settings `json:"settings"`
+	// Whitelist are the addresses allowed to access the app.
+	Whitelist []string `json:"whitelist"`
+	// Domains are the domains routed to the app.
+	Domains []string `json:"domains"`
+	// Certs maps certificate names to the app domains attached to them.
+	Certs map[string][]string `json:"certs"`
+	// TLS determines if the router enforces https-only requests to the app.
+	TLS *bool `json:"tls"`
+	// Collaborators are the users with access to the app.
+	Collaborators []string `json:"collaborators"`
+	// Build is the image and procfile deployed to the app.
+	Build *Build `json:"build"`
+	// Scale is the number of replicas of each process type.
+	Scale map[string]int `json:"scale"`
+}
+
+// Settings is the desired state of an app's settings. Nil fields are left unmanaged.
+type Settings struct {
+	Maintenance *bool                     `json:"maintenance"`
+	Routable    *bool                     `json:"routable"`
+	Autoscale   map[string]*api.Autoscale `json:"autoscale"`
+	Labels      api.Labels                `json:"labels"`
+}
+
+// Build is the desired build of an app.
+type Build struct {
+	Image string `json:"image"`
+	// Procfile maps process types to commands. If it is nil, it is left unmanaged.
+	Procfile map[string]string `json:"procfile"`
+}
+
+// Parse parses a manifest in either YAML or JSON format.
+func Parse(data []byte) (Manifest, error) {
+	m := Manifest{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := m.validate(); err != nil {
+		return Manifest{}, err
+	}
+
+	return m, nil
+}
+
+// Load reads and parses a manifest file in either YAML or JSON format.
+func Load(path string) (Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	return Parse(data)
+}
+
+func (m Manifest) validate() error {
+	if m.Version != 0 && m.Version != Version {
+		return ErrUnsupportedVersion
+	}
+
+	if m.App == "" {
+		return ErrMissingApp
+	}
+
+	return nil
+}
@@ -0,0 +1,622 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/sealed"
+)
+
+const manifestYAMLFixture string = `
+version: 1
+app: example-go
+config:
+  FOO: baz
+  NEW: "y"
+memory:
+  web: 1G
+settings:
+  maintenance: false
+  labels:
+    team: core
+whitelist:
+  - 1.2.3.4
+domains:
+  - example.com
+  - new.example.com
+certs:
+  example-com:
+    - example.com
+tls: true
+collaborators:
+  - alice
+build:
+  image: example/go:v2
+scale:
+  web: 3
+`
+
+const manifestJSONFixture string = `{
+  "version": 1,
+  "app": "example-go",
+  "config": {"FOO": "baz", "NEW": "y"},
+  "memory": {"web": "1G"},
+  "settings": {"maintenance": false, "labels": {"team": "core"}},
+  "whitelist": ["1.2.3.4"],
+  "domains": ["example.com", "new.example.com"],
+  "certs": {"example-com": ["example.com"]},
+  "tls": true,
+  "collaborators": ["alice"],
+  "build": {"image": "example/go:v2"},
+  "scale": {"web": 3}
+}`
+
+const configFixture string = `
+{
+    "owner": "test",
+    "app": "example-go",
+    "values": {
+      "API_TOKEN": "s3cret",
+      "FOO": "bar",
+      "OLD": "x"
+    },
+    "memory": {
+      "web": "1G"
+    },
+    "created": "2014-01-01T00:00:00UTC",
+    "updated": "2014-01-01T00:00:00UTC",
+    "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"
+}`
+
+const appFixture string = `
+{
+    "created": "2014-01-01T00:00:00UTC",
+    "id": "example-go",
+    "owner": "test",
+    "updated": "2014-01-01T00:00:00UTC",
+    "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"
+}`
+
+const settingsFixture string = `
+{
+    "owner": "test",
+    "app": "example-go",
+    "routable": true,
+    "created": "2014-01-01T00:00:00UTC",
+    "updated": "2014-01-01T00:00:00UTC",
+    "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"
+}`
+
+const whitelistFixture string = `{"addresses": ["1.2.3.4", "0.0.0.0/0"]}`
+
+const domainsFixture string = `
+{
+    "count": 2,
+    "next": null,
+    "previous": null,
+    "results": [
+        {"app": "example-go", "domain": "example.com"},
+        {"app": "example-go", "domain": "old.example.com"}
+    ]
+}`
+
+const certsFixture string = `
+{
+    "count": 1,
+    "next": null,
+    "previous": null,
+    "results": [
+        {"name": "example-com", "domains": ["other.com"]}
+    ]
+}`
+
+const tlsFixture string = `{"app": "example-go", "https_enforced": false}`
+
+const permsFixture string = `{"users": ["bob"]}`
+
+// buildsFixture lists a newer build than the one running, which the app was rolled back from.
+const buildsFixture string = `
+{
+    "count": 2,
+    "next": null,
+    "previous": null,
+    "results": [
+        {
+            "app": "example-go",
+            "image": "example/go:v3",
+            "procfile": {"web": "./server"},
+            "uuid": "0c9f1e2d-7b3a-4e5f-8a6b-9c0d1e2f3a4b"
+        },
+        {
+            "app": "example-go",
+            "image": "example/go:v1",
+            "procfile": {"web": "./server"},
+            "uuid": "5e8d7c6b-3a2f-4b1e-9d0c-8b7a6f5e4d3c"
+        }
+    ]
+}`
+
+const releasesFixture string = `
+{
+    "count": 1,
+    "next": null,
+    "previous": null,
+    "results": [
+        {
+            "app": "example-go",
+            "version": 4,
+            "build": "5e8d7c6b-3a2f-4b1e-9d0c-8b7a6f5e4d3c"
+        }
+    ]
+}`
+
+// podsFixture lists a terminating pod and a pod of an older release, which aren't counted in
+// the app's scale.
+const podsFixture string = `
+{
+    "count": 3,
+    "next": null,
+    "previous": null,
+    "results": [
+        {
+            "release": "v2",
+            "type": "web",
+            "name": "example-go-v2-web-45678",
+            "state": "up",
+            "started": "2016-02-13T00:47:52"
+        },
+        {
+            "release": "v2",
+            "type": "web",
+            "name": "example-go-v2-web-12345",
+            "state": "terminating",
+            "started": "2016-02-13T00:47:52"
+        },
+        {
+            "release": "v1",
+            "type": "web",
+            "name": "example-go-v1-web-98765",
+            "state": "up",
+            "started": "2016-02-12T00:47:52"
+        }
+    ]
+}`
+
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	if req.Method == "GET" {
+		fixtures := map[string]string{
+			"/v2/apps/example-go/":           appFixture,
+			"/v2/apps/example-go/config/":    configFixture,
+			"/v2/apps/example-go/settings/":  settingsFixture,
+			"/v2/apps/example-go/whitelist/": whitelistFixture,
+			"/v2/apps/example-go/domains/":   domainsFixture,
+			"/v2/certs/":                     certsFixture,
+			"/v2/apps/example-go/tls/":       tlsFixture,
+			"/v2/apps/example-go/perms/":     permsFixture,
+			"/v2/apps/example-go/builds/":    buildsFixture,
+			"/v2/apps/example-go/releases/":  releasesFixture,
+			"/v2/apps/example-go/pods/":      podsFixture,
+		}
+
+		if fixture, ok := fixtures[req.URL.Path]; ok {
+			res.Write([]byte(fixture))
+			return
+		}
+
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body))
+	f.mu.Unlock()
+
+	if req.Method == "DELETE" {
+		res.WriteHeader(http.StatusNoContent)
+		res.Write(nil)
+		return
+	}
+
+	res.WriteHeader(http.StatusCreated)
+	res.Write([]byte(`{}`))
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tls := true
+	maintenance := false
+	expected := Manifest{
+		Version:       1,
+		App:           "example-go",
+		Config:        map[string]string{"FOO": "baz", "NEW": "y"},
+		Memory:        map[string]string{"web": "1G"},
+		Settings:      &Settings{Maintenance: &maintenance, Labels: api.Labels{"team": "core"}},
+		Whitelist:     []string{"1.2.3.4"},
+		Domains:       []string{"example.com", "new.example.com"},
+		Certs:         map[string][]string{"example-com": {"example.com"}},
+		TLS:           &tls,
+		Collaborators: []string{"alice"},
+		Build:         &Build{Image: "example/go:v2"},
+		Scale:         map[string]int{"web": 3},
+	}
+
+	for _, fixture := range []string{manifestYAMLFixture, manifestJSONFixture} {
+		actual, err := Parse([]byte(fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected %v, Got %v", expected, actual)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Parse([]byte("version: 1\n")); err != ErrMissingApp {
+		t.Errorf("Expected %v, Got %v", ErrMissingApp, err)
+	}
+
+	if _, err := Parse([]byte("version: 2\napp: example-go\n")); err != ErrUnsupportedVersion {
+		t.Errorf("Expected %v, Got %v", ErrUnsupportedVersion, err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Parse([]byte(manifestYAMLFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := Plan(deis, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{Section: SectionConfig, Action: Update, Key: "FOO", Old: "bar", New: "baz"},
+		{Section: SectionConfig, Action: Add, Key: "NEW", New: "y"},
+		{Section: SectionConfig, Action: Remove, Key: "API_TOKEN", Old: "s3cret"},
+		{Section: SectionConfig, Action: Remove, Key: "OLD", Old: "x"},
+		{Section: SectionSettings, Action: Add, Key: "maintenance", New: "false"},
+		{Section: SectionLabels, Action: Add, Key: "team", New: "core"},
+		{Section: SectionWhitelist, Action: Remove, Key: "0.0.0.0/0"},
+		{Section: SectionDomains, Action: Add, Key: "new.example.com"},
+		{Section: SectionDomains, Action: Remove, Key: "old.example.com"},
+		{Section: SectionCerts, Action: Add, Key: "example.com", New: "example-com"},
+		{Section: SectionTLS, Action: Update, Key: "https_enforced", Old: "false", New: "true"},
+		{Section: SectionCollaborators, Action: Add, Key: "alice"},
+		{Section: SectionCollaborators, Action: Remove, Key: "bob"},
+		{Section: SectionBuild, Action: Update, Key: "image", Old: "example/go:v1", New: "example/go:v2"},
+		{Section: SectionScale, Action: Update, Key: "web", Old: "1", New: "3"},
+	}
+
+	if !reflect.DeepEqual(expected, cs.Changes) {
+		t.Errorf("Expected %v, Got %v", expected, cs.Changes)
+	}
+
+	if strings.Contains(cs.String(), "s3cret") || !strings.Contains(cs.String(), "- config API_TOKEN: ********") {
+		t.Errorf("Expected API_TOKEN to be masked, Got %s", cs.String())
+	}
+
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no changes to be made, Got %v", handler.requests)
+	}
+}
+
+func TestPlanUnchanged(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := Manifest{
+		App:       "example-go",
+		Config:    map[string]string{"API_TOKEN": "s3cret", "FOO": "bar", "OLD": "x"},
+		Whitelist: []string{"0.0.0.0/0", "1.2.3.4"},
+		Scale:     map[string]int{"web": 1},
+	}
+
+	cs, err := Plan(deis, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cs.Empty() {
+		t.Errorf("Expected no changes, Got %v", cs.Changes)
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Parse([]byte(manifestYAMLFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := Plan(deis, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Apply(deis, cs); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`POST /v2/apps/example-go/perms/ {"username":"alice"}`,
+		`POST /v2/apps/example-go/settings/ {"maintenance":false,"label":{"team":"core"}}`,
+		`POST /v2/apps/example-go/config/ {"values":{"API_TOKEN":null,"FOO":"baz","NEW":"y","OLD":null}}`,
+		`POST /v2/apps/example-go/builds/ {"image":"example/go:v2","procfile":{"web":"./server"}}`,
+		`POST /v2/apps/example-go/scale/ {"web":3}`,
+		`POST /v2/apps/example-go/domains/ {"domain":"new.example.com"}`,
+		`POST /v2/certs/example-com/domain/ {"domain":"example.com"}`,
+		`POST /v2/apps/example-go/tls/ {"https_enforced":true}`,
+		`DELETE /v2/apps/example-go/domains/old.example.com `,
+		`DELETE /v2/apps/example-go/whitelist/ {"addresses":["0.0.0.0/0"]}`,
+		`DELETE /v2/apps/example-go/perms/bob `,
+	}
+
+	if !reflect.DeepEqual(expected, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expected, handler.requests)
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := Export(deis, "example-go", ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routable := true
+	tls := false
+	expected := Manifest{
+		Version:      1,
+		App:          "example-go",
+		Owner:        "test",
+		Config:       map[string]string{"API_TOKEN": "s3cret", "FOO": "bar", "OLD": "x"},
+		Memory:       map[string]string{"web": "1G"},
+		CPU:          map[string]string{},
+		Healthchecks: map[string]*api.Healthchecks{},
+		Tags:         map[string]string{},
+		Settings: &Settings{
+			Routable:  &routable,
+			Autoscale: map[string]*api.Autoscale{},
+			Labels:    api.Labels{},
+		},
+		Whitelist:     []string{"1.2.3.4", "0.0.0.0/0"},
+		Domains:       []string{"example.com", "old.example.com"},
+		Certs:         map[string][]string{},
+		TLS:           &tls,
+		Collaborators: []string{"bob"},
+		Build:         &Build{Image: "example/go:v1", Procfile: map[string]string{"web": "./server"}},
+		Scale:         map[string]int{"web": 1},
+	}
+
+	if actual.Build == nil || actual.Build.Image != "example/go:v1" {
+		t.Errorf("Expected the running build example/go:v1 rather than the newest, Got %v", actual.Build)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+
+	for _, format := range []Format{YAML, JSON} {
+		out, err := Marshal(actual, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := Parse(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(expected, m) {
+			t.Errorf("Expected %v, Got %v", expected, m)
+		}
+
+		cs, err := Plan(deis, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !cs.Empty() {
+			t.Errorf("Expected no changes, Got %v", cs.Changes)
+		}
+	}
+}
+
+func TestExportRedacted(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Export(deis, "example-go", ExportOptions{Secrets: SecretsRedact})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"API_TOKEN": Redacted, "FOO": "bar", "OLD": "x"}
+	if !reflect.DeepEqual(expected, m.Config) {
+		t.Errorf("Expected %v, Got %v", expected, m.Config)
+	}
+
+	cs, err := Plan(deis, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cs.Empty() {
+		t.Errorf("Expected no changes, Got %v", cs.Changes)
+	}
+
+	m.Config["DB_PASSWORD"] = Redacted
+	if _, err = Plan(deis, m); err != ErrRedacted {
+		t.Errorf("Expected %v, Got %v", ErrRedacted, err)
+	}
+}
+
+func TestExportEncrypted(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Export(deis, "example-go", ExportOptions{Secrets: SecretsEncrypt}); err != ErrMissingCipher {
+		t.Errorf("Expected %v, Got %v", ErrMissingCipher, err)
+	}
+
+	key, err := sealed.GenerateKey("2016")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := sealed.NewKeyring(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := Export(deis, "example-go", ExportOptions{Secrets: SecretsEncrypt, Cipher: cipher})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sealed.KeyID(exported.Config["API_TOKEN"]) != "2016" {
+		t.Errorf("Expected API_TOKEN to be sealed with 2016, Got %s", exported.Config["API_TOKEN"])
+	}
+	if !reflect.DeepEqual([]string{"API_TOKEN"}, exported.Sealed) {
+		t.Errorf("Expected [API_TOKEN] to be sealed, Got %v", exported.Sealed)
+	}
+
+	if _, err = Plan(deis, exported); err != ErrEncrypted {
+		t.Errorf("Expected %v, Got %v", ErrEncrypted, err)
+	}
+
+	out, err := Marshal(exported, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := sealed.GenerateKey("2017")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := sealed.NewKeyring(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedErr := sealed.ErrUnknownKey{Key: "API_TOKEN", ID: "2016"}
+	if _, err = Decrypt(m, other); err != expectedErr {
+		t.Errorf("Expected %v, Got %v", expectedErr, err)
+	}
+
+	// After a rotation, the keyring still opens values sealed with its old key.
+	rotated, err := sealed.NewKeyring(otherKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decrypt(m, rotated); err != nil {
+		t.Errorf("Expected nil, Got %v", err)
+	}
+
+	if m, err = Decrypt(m, cipher); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Config["API_TOKEN"] != "s3cret" {
+		t.Errorf("Expected s3cret, Got %s", m.Config["API_TOKEN"])
+	}
+
+	cs, err := Plan(deis, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cs.Empty() {
+		t.Errorf("Expected no changes, Got %v", cs.Changes)
+	}
+}
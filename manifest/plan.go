@@ -0,0 +1,793 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/appsettings"
+	"github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/controller-sdk-go/certs"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/domains"
+	"github.com/deis/controller-sdk-go/perms"
+	"github.com/deis/controller-sdk-go/ps"
+	"github.com/deis/controller-sdk-go/releasediff"
+	"github.com/deis/controller-sdk-go/sensitive"
+	"github.com/deis/controller-sdk-go/tls"
+	"github.com/deis/controller-sdk-go/whitelist"
+)
+
+// listLimit is the number of results requested when listing live state.
+const listLimit = 1000
+
+// Sections of a manifest that changes apply to.
+const (
+	SectionConfig        = "config"
+	SectionMemory        = "memory"
+	SectionCPU           = "cpu"
+	SectionHealthchecks  = "healthchecks"
+	SectionTags          = "tags"
+	SectionSettings      = "settings"
+	SectionAutoscale     = "autoscale"
+	SectionLabels        = "labels"
+	SectionWhitelist     = "whitelist"
+	SectionDomains       = "domains"
+	SectionCerts         = "certs"
+	SectionTLS           = "tls"
+	SectionCollaborators = "collaborators"
+	SectionBuild         = "build"
+	SectionScale         = "scale"
+)
+
+// Action is the kind of change made to an item in an app.
+type Action string
+
+const (
+	// Add creates an item that does not exist in the app.
+	Add Action = "add"
+	// Update modifies an item that already exists in the app.
+	Update Action = "update"
+	// Remove deletes an item from the app.
+	Remove Action = "remove"
+)
+
+// Change is a single difference between a manifest and the live app.
+type Change struct {
+	Section string
+	Action  Action
+	Key     string
+	// Old is the live value, which is empty for added items.
+	Old string
+	// New is the desired value, which is empty for removed items.
+	New string
+}
+
+// String displays the Change in a readable format.
+func (c Change) String() string {
+	switch c.Action {
+	case Add:
+		return fmt.Sprintf("+ %s %s: %s", c.Section, c.Key, c.New)
+	case Remove:
+		return fmt.Sprintf("- %s %s: %s", c.Section, c.Key, c.Old)
+	default:
+		return fmt.Sprintf("~ %s %s: %s => %s", c.Section, c.Key, c.Old, c.New)
+	}
+}
+
+// Changeset is the set of changes required to bring an app in line with a manifest.
+type Changeset struct {
+	App     string
+	Changes []Change
+
+	desired Manifest
+	live    Manifest
+}
+
+// Empty returns true if the app already matches the manifest.
+func (cs Changeset) Empty() bool {
+	return len(cs.Changes) == 0
+}
+
+// String displays the Changeset in a readable format, one change per line, with sensitive
+// config values masked.
+func (cs Changeset) String() string {
+	return cs.Render(sensitive.Masker{})
+}
+
+// Render displays the Changeset in a readable format, one change per line, masking config
+// values with m.
+func (cs Changeset) Render(m sensitive.Masker) string {
+	lines := make([]string, len(cs.Changes))
+	for i, change := range cs.Changes {
+		if change.Section == SectionConfig {
+			change.Old, change.New = m.Change(change.Key, change.Old, change.New)
+		}
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (cs Changeset) filter(section string, actions ...Action) []Change {
+	var changes []Change
+	for _, change := range cs.Changes {
+		if change.Section != section {
+			continue
+		}
+		for _, action := range actions {
+			if change.Action == action {
+				changes = append(changes, change)
+				break
+			}
+		}
+	}
+	return changes
+}
+
+// Plan compares a manifest to the live state of its app and returns the changes needed to
+// bring the app in line with the manifest. Only the sections present in the manifest are read.
+func Plan(c *deis.Client, m Manifest) (Changeset, error) {
+	if err := m.validate(); err != nil {
+		return Changeset{}, err
+	}
+
+	live, err := fetch(c, m)
+	if err != nil {
+		return Changeset{}, err
+	}
+
+	if err = checkSecrets(live, m); err != nil {
+		return Changeset{}, err
+	}
+	m = unredact(live, m)
+
+	return Changeset{
+		App:     m.App,
+		Changes: diff(live, m),
+		desired: m,
+		live:    live,
+	}, nil
+}
+
+// fetch retrieves the live state of the sections managed by m.
+func fetch(c *deis.Client, m Manifest) (Manifest, error) {
+	live := Manifest{Version: Version, App: m.App}
+
+	if m.Config != nil || m.Memory != nil || m.CPU != nil || m.Healthchecks != nil || m.Tags != nil {
+		cfg, err := config.List(c, m.App)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		live.Config = toStrings(cfg.Values)
+		live.Memory = toStrings(cfg.Memory)
+		live.CPU = toStrings(cfg.CPU)
+		live.Tags = toStrings(cfg.Tags)
+		live.Healthchecks = cfg.Healthcheck
+		if live.Healthchecks == nil {
+			live.Healthchecks = map[string]*api.Healthchecks{}
+		}
+	}
+
+	if m.Settings != nil {
+		settings, err := appsettings.List(c, m.App)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		live.Settings = &Settings{
+			Maintenance: settings.Maintenance,
+			Routable:    settings.Routable,
+			Autoscale:   settings.Autoscale,
+			Labels:      settings.Label,
+		}
+		if live.Settings.Autoscale == nil {
+			live.Settings.Autoscale = map[string]*api.Autoscale{}
+		}
+		if live.Settings.Labels == nil {
+			live.Settings.Labels = api.Labels{}
+		}
+	}
+
+	if m.Whitelist != nil {
+		w, err := whitelist.List(c, m.App)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		live.Whitelist = append([]string{}, w.Addresses...)
+	}
+
+	if m.Domains != nil || m.Certs != nil {
+		ds, _, err := domains.List(c, m.App, listLimit)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		live.Domains = []string{}
+		for _, d := range ds {
+			live.Domains = append(live.Domains, d.Domain)
+		}
+	}
+
+	if m.Certs != nil {
+		cs, _, err := certs.List(c, listLimit)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		live.Certs = map[string][]string{}
+		for _, cert := range cs {
+			for _, d := range cert.Domains {
+				if contains(live.Domains, d) {
+					live.Certs[cert.Name] = append(live.Certs[cert.Name], d)
+				}
+			}
+		}
+	}
+
+	if m.TLS != nil {
+		t, err := tls.Info(c, m.App)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		enforced := t.HTTPSEnforced != nil && *t.HTTPSEnforced
+		live.TLS = &enforced
+	}
+
+	if m.Collaborators != nil {
+		users, err := perms.List(c, m.App)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		live.Collaborators = append([]string{}, users...)
+	}
+
+	if m.Build != nil {
+		// The build of the latest release is the one running. After a rollback it's older than
+		// the newest build.
+		build, _, err := releasediff.CurrentBuild(c, m.App)
+		if err != nil {
+			return Manifest{}, err
+		}
+		if build.UUID != "" {
+			live.Build = &Build{Image: build.Image, Procfile: build.Procfile}
+		}
+	}
+
+	if m.Scale != nil {
+		pods, _, err := ps.List(c, m.App, listLimit)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Manifest{}, err
+		}
+		// Terminating pods and the old pods of a rollout aren't part of the app's scale.
+		live.Scale = ps.Replicas(pods)
+	}
+
+	return live, nil
+}
+
+// diff returns the changes needed to make live match desired.
+func diff(live, desired Manifest) []Change {
+	var changes []Change
+
+	if desired.Config != nil {
+		changes = append(changes, diffMaps(SectionConfig, fromStrings(live.Config), fromStrings(desired.Config))...)
+	}
+	if desired.Memory != nil {
+		changes = append(changes, diffMaps(SectionMemory, fromStrings(live.Memory), fromStrings(desired.Memory))...)
+	}
+	if desired.CPU != nil {
+		changes = append(changes, diffMaps(SectionCPU, fromStrings(live.CPU), fromStrings(desired.CPU))...)
+	}
+	if desired.Healthchecks != nil {
+		changes = append(changes, diffMaps(SectionHealthchecks, fromHealthchecks(live.Healthchecks),
+			fromHealthchecks(desired.Healthchecks))...)
+	}
+	if desired.Tags != nil {
+		changes = append(changes, diffMaps(SectionTags, fromStrings(live.Tags), fromStrings(desired.Tags))...)
+	}
+
+	if desired.Settings != nil {
+		changes = append(changes, diffSettings(live.Settings, desired.Settings)...)
+	}
+
+	if desired.Whitelist != nil {
+		changes = append(changes, diffSets(SectionWhitelist, live.Whitelist, desired.Whitelist)...)
+	}
+	if desired.Domains != nil {
+		changes = append(changes, diffSets(SectionDomains, live.Domains, desired.Domains)...)
+	}
+	if desired.Certs != nil {
+		changes = append(changes, diffMaps(SectionCerts, byDomain(live.Certs), byDomain(desired.Certs))...)
+	}
+
+	if desired.TLS != nil && (live.TLS == nil || *live.TLS != *desired.TLS) {
+		old := "false"
+		if live.TLS != nil {
+			old = strconv.FormatBool(*live.TLS)
+		}
+		changes = append(changes, Change{
+			Section: SectionTLS,
+			Action:  Update,
+			Key:     "https_enforced",
+			Old:     old,
+			New:     strconv.FormatBool(*desired.TLS),
+		})
+	}
+
+	if desired.Collaborators != nil {
+		changes = append(changes, diffSets(SectionCollaborators, live.Collaborators, desired.Collaborators)...)
+	}
+
+	if desired.Build != nil {
+		changes = append(changes, diffBuild(live.Build, desired.Build)...)
+	}
+
+	if desired.Scale != nil {
+		for _, procType := range sortedKeys(fromInts(desired.Scale)) {
+			if live.Scale[procType] != desired.Scale[procType] {
+				changes = append(changes, Change{
+					Section: SectionScale,
+					Action:  Update,
+					Key:     procType,
+					Old:     strconv.Itoa(live.Scale[procType]),
+					New:     strconv.Itoa(desired.Scale[procType]),
+				})
+			}
+		}
+	}
+
+	return changes
+}
+
+func diffSettings(live, desired *Settings) []Change {
+	if live == nil {
+		live = &Settings{}
+	}
+
+	var changes []Change
+	if desired.Maintenance != nil && !reflect.DeepEqual(live.Maintenance, desired.Maintenance) {
+		changes = append(changes, diffValue(SectionSettings, "maintenance", live.Maintenance, *desired.Maintenance))
+	}
+	if desired.Routable != nil && !reflect.DeepEqual(live.Routable, desired.Routable) {
+		changes = append(changes, diffValue(SectionSettings, "routable", live.Routable, *desired.Routable))
+	}
+	if desired.Autoscale != nil {
+		changes = append(changes, diffMaps(SectionAutoscale, fromAutoscales(live.Autoscale),
+			fromAutoscales(desired.Autoscale))...)
+	}
+	if desired.Labels != nil {
+		changes = append(changes, diffMaps(SectionLabels, live.Labels, desired.Labels)...)
+	}
+	return changes
+}
+
+// diffValue creates a change for a single setting, where a nil live value has never been set.
+func diffValue(section, key string, live *bool, desired bool) Change {
+	if live == nil {
+		return Change{Section: section, Action: Add, Key: key, New: strconv.FormatBool(desired)}
+	}
+	return Change{
+		Section: section,
+		Action:  Update,
+		Key:     key,
+		Old:     strconv.FormatBool(*live),
+		New:     strconv.FormatBool(desired),
+	}
+}
+
+func diffBuild(live, desired *Build) []Change {
+	if live == nil {
+		live = &Build{}
+	}
+
+	var changes []Change
+	if live.Image != desired.Image {
+		action := Update
+		if live.Image == "" {
+			action = Add
+		}
+		changes = append(changes, Change{
+			Section: SectionBuild,
+			Action:  action,
+			Key:     "image",
+			Old:     live.Image,
+			New:     desired.Image,
+		})
+	}
+	if desired.Procfile != nil && !reflect.DeepEqual(live.Procfile, desired.Procfile) &&
+		!(len(live.Procfile) == 0 && len(desired.Procfile) == 0) {
+		changes = append(changes, Change{
+			Section: SectionBuild,
+			Action:  Update,
+			Key:     "procfile",
+			Old:     display(live.Procfile),
+			New:     display(desired.Procfile),
+		})
+	}
+	return changes
+}
+
+// diffMaps compares two maps. Keys in desired are added or updated and keys only in live are removed.
+func diffMaps(section string, live, desired map[string]interface{}) []Change {
+	var changes []Change
+
+	for _, key := range sortedKeys(desired) {
+		have, ok := live[key]
+		if !ok {
+			changes = append(changes, Change{Section: section, Action: Add, Key: key, New: display(desired[key])})
+		} else if !reflect.DeepEqual(have, desired[key]) {
+			changes = append(changes, Change{
+				Section: section,
+				Action:  Update,
+				Key:     key,
+				Old:     display(have),
+				New:     display(desired[key]),
+			})
+		}
+	}
+
+	for _, key := range sortedKeys(live) {
+		if _, ok := desired[key]; !ok {
+			changes = append(changes, Change{Section: section, Action: Remove, Key: key, Old: display(live[key])})
+		}
+	}
+
+	return changes
+}
+
+// diffSets compares two lists of unique items, ignoring their order.
+func diffSets(section string, live, desired []string) []Change {
+	var changes []Change
+
+	for _, item := range sortedUnique(desired) {
+		if !contains(live, item) {
+			changes = append(changes, Change{Section: section, Action: Add, Key: item})
+		}
+	}
+
+	for _, item := range sortedUnique(live) {
+		if !contains(desired, item) {
+			changes = append(changes, Change{Section: section, Action: Remove, Key: item})
+		}
+	}
+
+	return changes
+}
+
+// Apply makes the changes in a changeset to its app.
+//
+// Changes are made in an order that avoids disrupting the app: collaborators and whitelisted
+// addresses are added before anything else and removed last, config is set before a new build
+// is deployed, the app is scaled after it is deployed, and certificates are attached to domains
+// before https is enforced. Settings, config and whitelist changes are each made in a single call.
+func Apply(c *deis.Client, cs Changeset) error {
+	steps := []func(*deis.Client, Changeset) error{
+		addCollaborators,
+		addWhitelist,
+		applySettings,
+		applyConfig,
+		applyBuild,
+		applyScale,
+		addDomains,
+		attachCerts,
+		applyTLS,
+		detachCerts,
+		removeDomains,
+		removeWhitelist,
+		removeCollaborators,
+	}
+
+	for _, step := range steps {
+		if err := step(c, cs); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addCollaborators(c *deis.Client, cs Changeset) error {
+	for _, change := range cs.filter(SectionCollaborators, Add) {
+		if err := perms.New(c, cs.App, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeCollaborators(c *deis.Client, cs Changeset) error {
+	for _, change := range cs.filter(SectionCollaborators, Remove) {
+		if err := perms.Delete(c, cs.App, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func addWhitelist(c *deis.Client, cs Changeset) error {
+	addresses := keys(cs.filter(SectionWhitelist, Add))
+	if len(addresses) == 0 {
+		return nil
+	}
+	_, err := whitelist.Add(c, cs.App, addresses)
+	return err
+}
+
+func removeWhitelist(c *deis.Client, cs Changeset) error {
+	addresses := keys(cs.filter(SectionWhitelist, Remove))
+	if len(addresses) == 0 {
+		return nil
+	}
+	return whitelist.Delete(c, cs.App, addresses)
+}
+
+func applySettings(c *deis.Client, cs Changeset) error {
+	settings := api.AppSettings{}
+	changed := false
+
+	for _, change := range cs.filter(SectionSettings, Add, Update) {
+		changed = true
+		switch change.Key {
+		case "maintenance":
+			settings.Maintenance = cs.desired.Settings.Maintenance
+		case "routable":
+			settings.Routable = cs.desired.Settings.Routable
+		}
+	}
+
+	for _, change := range cs.filter(SectionAutoscale, Add, Update, Remove) {
+		changed = true
+		if settings.Autoscale == nil {
+			settings.Autoscale = map[string]*api.Autoscale{}
+		}
+		// A nil rule unsets autoscaling for the process type.
+		settings.Autoscale[change.Key] = cs.desired.Settings.Autoscale[change.Key]
+	}
+
+	for _, change := range cs.filter(SectionLabels, Add, Update, Remove) {
+		changed = true
+		if settings.Label == nil {
+			settings.Label = api.Labels{}
+		}
+		settings.Label[change.Key] = cs.desired.Settings.Labels[change.Key]
+	}
+
+	if !changed {
+		return nil
+	}
+	_, err := appsettings.Set(c, cs.App, settings)
+	return err
+}
+
+func applyConfig(c *deis.Client, cs Changeset) error {
+	cfg := api.Config{}
+	changed := false
+
+	for _, change := range cs.filter(SectionConfig, Add, Update, Remove) {
+		changed = true
+		cfg.Values = setOrUnset(cfg.Values, change, cs.desired.Config)
+	}
+	for _, change := range cs.filter(SectionMemory, Add, Update, Remove) {
+		changed = true
+		cfg.Memory = setOrUnset(cfg.Memory, change, cs.desired.Memory)
+	}
+	for _, change := range cs.filter(SectionCPU, Add, Update, Remove) {
+		changed = true
+		cfg.CPU = setOrUnset(cfg.CPU, change, cs.desired.CPU)
+	}
+	for _, change := range cs.filter(SectionTags, Add, Update, Remove) {
+		changed = true
+		cfg.Tags = setOrUnset(cfg.Tags, change, cs.desired.Tags)
+	}
+	for _, change := range cs.filter(SectionHealthchecks, Add, Update, Remove) {
+		changed = true
+		if cfg.Healthcheck == nil {
+			cfg.Healthcheck = map[string]*api.Healthchecks{}
+		}
+		cfg.Healthcheck[change.Key] = cs.desired.Healthchecks[change.Key]
+	}
+
+	if !changed {
+		return nil
+	}
+	_, err := config.Set(c, cs.App, cfg)
+	return err
+}
+
+// setOrUnset adds the desired value of a change to a config patch, or nil if it is being removed.
+func setOrUnset(patch map[string]interface{}, change Change, desired map[string]string) map[string]interface{} {
+	if patch == nil {
+		patch = map[string]interface{}{}
+	}
+	if change.Action == Remove {
+		patch[change.Key] = nil
+	} else {
+		patch[change.Key] = desired[change.Key]
+	}
+	return patch
+}
+
+func applyBuild(c *deis.Client, cs Changeset) error {
+	if len(cs.filter(SectionBuild, Add, Update)) == 0 {
+		return nil
+	}
+
+	procfile := cs.desired.Build.Procfile
+	if procfile == nil && cs.live.Build != nil {
+		procfile = cs.live.Build.Procfile
+	}
+
+	_, err := builds.New(c, cs.App, cs.desired.Build.Image, procfile)
+	return err
+}
+
+func applyScale(c *deis.Client, cs Changeset) error {
+	changes := cs.filter(SectionScale, Update)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	targets := map[string]int{}
+	for _, change := range changes {
+		targets[change.Key] = cs.desired.Scale[change.Key]
+	}
+	return ps.Scale(c, cs.App, targets)
+}
+
+func addDomains(c *deis.Client, cs Changeset) error {
+	for _, change := range cs.filter(SectionDomains, Add) {
+		if _, err := domains.New(c, cs.App, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeDomains(c *deis.Client, cs Changeset) error {
+	for _, change := range cs.filter(SectionDomains, Remove) {
+		if err := domains.Delete(c, cs.App, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func attachCerts(c *deis.Client, cs Changeset) error {
+	for _, change := range cs.filter(SectionCerts, Add, Update) {
+		// A domain can only be attached to one certificate at a time.
+		if change.Action == Update {
+			if err := certs.Detach(c, change.Old, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+				return err
+			}
+		}
+		if err := certs.Attach(c, change.New, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func detachCerts(c *deis.Client, cs Changeset) error {
+	for _, change := range cs.filter(SectionCerts, Remove) {
+		if err := certs.Detach(c, change.Old, change.Key); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyTLS(c *deis.Client, cs Changeset) error {
+	if len(cs.filter(SectionTLS, Update)) == 0 {
+		return nil
+	}
+
+	var err error
+	if *cs.desired.TLS {
+		_, err = tls.Enable(c, cs.App)
+	} else {
+		_, err = tls.Disable(c, cs.App)
+	}
+	return err
+}
+
+// display formats a value for a Change. Strings are shown as is and everything else as JSON.
+func display(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+func toStrings(m map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	for k, v := range m {
+		out[k] = fmt.Sprint(v)
+	}
+	return out
+}
+
+func fromStrings(m map[string]string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func fromInts(m map[string]int) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func fromHealthchecks(m map[string]*api.Healthchecks) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func fromAutoscales(m map[string]*api.Autoscale) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// byDomain inverts a map of certificates to domains into a map of domains to certificates.
+func byDomain(certs map[string][]string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for cert, ds := range certs {
+		for _, d := range ds {
+			out[d] = cert
+		}
+	}
+	return out
+}
+
+func keys(changes []Change) []string {
+	var out []string
+	for _, change := range changes {
+		out = append(out, change.Key)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedUnique(items []string) []string {
+	out := []string{}
+	for _, item := range items {
+		if !contains(out, item) {
+			out = append(out, item)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func contains(items []string, search string) bool {
+	for _, item := range items {
+		if item == search {
+			return true
+		}
+	}
+	return false
+}
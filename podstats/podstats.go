@@ -0,0 +1,189 @@
+// Package podstats provides summaries of an app's pods for monitoring and alerting.
+//
+// Summarize describes a single listing of pods, grouped by process type. A Tracker compares
+// successive listings to find pods in a crash loop, whose start time keeps resetting as their
+// containers are restarted.
+package podstats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/watch"
+)
+
+const (
+	// DefaultRestarts is the number of restarts within the window that marks a crash loop.
+	DefaultRestarts = 3
+	// DefaultWindow is the time over which restarts are counted.
+	DefaultWindow = 10 * time.Minute
+)
+
+// Counts are pod counts by state and by release.
+type Counts struct {
+	Total    int                  `json:"total"`
+	States   map[api.PodState]int `json:"states"`
+	Releases map[string]int       `json:"releases"`
+}
+
+func newCounts() Counts {
+	return Counts{States: map[api.PodState]int{}, Releases: map[string]int{}}
+}
+
+func (c *Counts) add(pod api.Pods) {
+	c.Total++
+	c.States[api.ParsePodState(pod.State)]++
+	c.Releases[pod.Release]++
+}
+
+// TypeSummary summarizes the pods of a process type.
+type TypeSummary struct {
+	Type string `json:"type"`
+	Counts
+	// Stale are the pods of the type which are not running the current release.
+	Stale api.PodsList `json:"stale"`
+}
+
+// Summary summarizes the pods of an app.
+type Summary struct {
+	// Release is the current release, such as "v3". Pods running other releases are stale.
+	Release string `json:"release"`
+	Counts
+	// Types are the summaries of each process type, sorted by type.
+	Types []TypeSummary `json:"types"`
+}
+
+// Stale returns every stale pod in the app, sorted by type and then by name.
+func (s Summary) Stale() api.PodsList {
+	var stale api.PodsList
+	for _, t := range s.Types {
+		stale = append(stale, t.Stale...)
+	}
+	return stale
+}
+
+// Summarize groups pods by process type and counts them by state and release. Pods which are
+// not running release are marked as stale. If release is empty, the newest release running
+// in the pods is used.
+func Summarize(pods api.PodsList, release string) Summary {
+	if release == "" {
+		release = watch.Newest(pods)
+	}
+
+	summary := Summary{Release: release, Counts: newCounts()}
+	// index maps process types to their position in summary.Types.
+	index := map[string]int{}
+
+	for _, pod := range pods {
+		i, ok := index[pod.Type]
+		if !ok {
+			i = len(summary.Types)
+			index[pod.Type] = i
+			summary.Types = append(summary.Types, TypeSummary{Type: pod.Type, Counts: newCounts()})
+		}
+
+		summary.add(pod)
+		summary.Types[i].add(pod)
+		if pod.Release != release {
+			summary.Types[i].Stale = append(summary.Types[i].Stale, pod)
+		}
+	}
+
+	sort.Sort(byType(summary.Types))
+	for _, t := range summary.Types {
+		sort.Sort(t.Stale)
+	}
+
+	return summary
+}
+
+type byType []TypeSummary
+
+func (t byType) Len() int           { return len(t) }
+func (t byType) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t byType) Less(i, j int) bool { return t[i].Type < t[j].Type }
+
+// CrashLoop is a pod which has restarted too often.
+type CrashLoop struct {
+	// Pod is the pod as last observed.
+	Pod api.Pods `json:"pod"`
+	// Restarts is the number of restarts observed within the window.
+	Restarts int `json:"restarts"`
+	// Since is when the oldest of those restarts was observed.
+	Since time.Time `json:"since"`
+}
+
+// Tracker detects crash loops across successive listings of an app's pods. A pod restarts
+// when its start time changes between listings. The zero value is not usable; use NewTracker.
+type Tracker struct {
+	restarts int
+	window   time.Duration
+	started  map[string]time.Time
+	resets   map[string][]time.Time
+}
+
+// NewTracker creates a Tracker which reports pods that restarted at least restarts times
+// within window. If either is zero, DefaultRestarts or DefaultWindow is used.
+func NewTracker(restarts int, window time.Duration) *Tracker {
+	if restarts <= 0 {
+		restarts = DefaultRestarts
+	}
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	return &Tracker{
+		restarts: restarts,
+		window:   window,
+		started:  map[string]time.Time{},
+		resets:   map[string][]time.Time{},
+	}
+}
+
+// Observe records a listing of pods taken at now and returns the pods which are crash
+// looping, sorted by name. Pods missing from the listing are forgotten.
+func (t *Tracker) Observe(pods api.PodsList, now time.Time) []CrashLoop {
+	seen := make(map[string]bool, len(pods))
+	var loops []CrashLoop
+
+	for _, pod := range pods {
+		seen[pod.Name] = true
+		if pod.Started.Time == nil {
+			continue
+		}
+		started := *pod.Started.Time
+
+		if last, ok := t.started[pod.Name]; ok && !last.Equal(started) {
+			t.resets[pod.Name] = append(t.resets[pod.Name], now)
+		}
+		t.started[pod.Name] = started
+
+		// Drop restarts which have fallen out of the window.
+		resets := t.resets[pod.Name]
+		for len(resets) > 0 && now.Sub(resets[0]) > t.window {
+			resets = resets[1:]
+		}
+		t.resets[pod.Name] = resets
+
+		if len(resets) >= t.restarts {
+			loops = append(loops, CrashLoop{Pod: pod, Restarts: len(resets), Since: resets[0]})
+		}
+	}
+
+	for name := range t.started {
+		if !seen[name] {
+			delete(t.started, name)
+			delete(t.resets, name)
+		}
+	}
+
+	sort.Sort(byName(loops))
+	return loops
+}
+
+type byName []CrashLoop
+
+func (l byName) Len() int           { return len(l) }
+func (l byName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l byName) Less(i, j int) bool { return l[i].Pod.Name < l[j].Pod.Name }
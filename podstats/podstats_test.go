@@ -0,0 +1,125 @@
+package podstats
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deis/controller-sdk-go/api"
+	deistime "github.com/deis/controller-sdk-go/pkg/time"
+)
+
+func started(t time.Time) deistime.Time {
+	return deistime.Time{Time: &t}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	pods := api.PodsList{
+		{Release: "v3", Type: "worker", Name: "worker-a", State: "crashed"},
+		{Release: "v2", Type: "web", Name: "web-b", State: "up"},
+		{Release: "v3", Type: "web", Name: "web-a", State: "up"},
+		{Release: "v3", Type: "web", Name: "web-c", State: "starting"},
+	}
+
+	actual := Summarize(pods, "")
+
+	if actual.Release != "v3" {
+		t.Errorf("Expected v3, Got %s", actual.Release)
+	}
+
+	expected := Counts{
+		Total:    4,
+		States:   map[api.PodState]int{api.PodUp: 2, api.PodStarting: 1, api.PodCrashed: 1},
+		Releases: map[string]int{"v2": 1, "v3": 3},
+	}
+	if !reflect.DeepEqual(expected, actual.Counts) {
+		t.Errorf("Expected %v, Got %v", expected, actual.Counts)
+	}
+
+	if len(actual.Types) != 2 || actual.Types[0].Type != "web" || actual.Types[1].Type != "worker" {
+		t.Fatalf("Expected web and worker summaries, Got %v", actual.Types)
+	}
+
+	web := actual.Types[0]
+	if web.Total != 3 || web.States[api.PodUp] != 2 || web.Releases["v2"] != 1 {
+		t.Errorf("Expected 3 web pods with 2 up and 1 on v2, Got %v", web.Counts)
+	}
+
+	if stale := actual.Stale(); len(stale) != 1 || stale[0].Name != "web-b" {
+		t.Errorf("Expected web-b to be stale, Got %v", stale)
+	}
+
+	if stale := Summarize(pods, "v2").Stale(); len(stale) != 3 {
+		t.Errorf("Expected 3 stale pods, Got %v", stale)
+	}
+}
+
+func TestSummaryJSON(t *testing.T) {
+	t.Parallel()
+
+	summary := Summarize(api.PodsList{{Release: "v1", Type: "web", Name: "web-a", State: "up"}}, "v1")
+	summary.Types = nil
+
+	actual, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"release":"v1","total":1,"states":{"up":1},"releases":{"v1":1},"types":null}`
+	if string(actual) != expected {
+		t.Errorf("Expected %s, Got %s", expected, actual)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker(2, time.Minute)
+	now := time.Date(2016, 2, 13, 0, 0, 0, 0, time.UTC)
+
+	sample := func(offset time.Duration, webStarted time.Duration) []CrashLoop {
+		return tracker.Observe(api.PodsList{
+			{Type: "web", Name: "web-a", State: "up", Started: started(now.Add(webStarted))},
+			{Type: "worker", Name: "worker-a", State: "up", Started: started(now)},
+		}, now.Add(offset))
+	}
+
+	if loops := sample(0, 0); len(loops) != 0 {
+		t.Errorf("Expected no crash loops, Got %v", loops)
+	}
+	if loops := sample(10*time.Second, 5*time.Second); len(loops) != 0 {
+		t.Errorf("Expected no crash loops after one restart, Got %v", loops)
+	}
+
+	loops := sample(20*time.Second, 15*time.Second)
+	if len(loops) != 1 || loops[0].Pod.Name != "web-a" || loops[0].Restarts != 2 {
+		t.Fatalf("Expected web-a to be crash looping, Got %v", loops)
+	}
+	if !loops[0].Since.Equal(now.Add(10 * time.Second)) {
+		t.Errorf("Expected %v, Got %v", now.Add(10*time.Second), loops[0].Since)
+	}
+
+	// The restarts fall out of the window once the pod stays up.
+	if loops := sample(2*time.Minute, 15*time.Second); len(loops) != 0 {
+		t.Errorf("Expected no crash loops, Got %v", loops)
+	}
+}
+
+func TestTrackerForgetsPods(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker(1, time.Minute)
+	now := time.Date(2016, 2, 13, 0, 0, 0, 0, time.UTC)
+
+	tracker.Observe(api.PodsList{{Name: "web-a", Started: started(now)}}, now)
+	tracker.Observe(api.PodsList{}, now.Add(time.Second))
+
+	// A pod that disappeared and came back is not a restart.
+	loops := tracker.Observe(api.PodsList{{Name: "web-a", Started: started(now.Add(time.Second))}}, now.Add(2*time.Second))
+	if len(loops) != 0 {
+		t.Errorf("Expected no crash loops, Got %v", loops)
+	}
+}
@@ -0,0 +1,321 @@
+// Package preview provides short-lived preview apps, such as one per pull request.
+//
+// Create makes a preview app from a template app, copying its config and recording the
+// preview's owner, template and expiry in app labels. Reap deletes previews whose expiry has
+// passed. Only apps with a preview expiry label are ever deleted.
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/appsettings"
+	"github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/controller-sdk-go/config"
+)
+
+const (
+	// OwnerLabel is the app label recording who created a preview.
+	OwnerLabel = "preview-owner"
+	// TemplateLabel is the app label recording the template app of a preview.
+	TemplateLabel = "preview-template"
+	// ExpiresLabel is the app label recording when a preview expires, in RFC 3339 format.
+	ExpiresLabel = "preview-expires"
+
+	// DefaultTTL is how long a preview lives when no TTL is given.
+	DefaultTTL = 72 * time.Hour
+	// maxNameLength is the longest app name the controller accepts.
+	maxNameLength = 63
+	// listLimit is the number of apps requested when listing previews.
+	listLimit = 1000
+)
+
+var (
+	// ErrMissingTemplate is returned when a preview is created without a template app.
+	ErrMissingTemplate = errors.New("A template app is required")
+	// ErrMissingID is returned when a preview is created without an ID, or with an ID that
+	// would name the preview after its template.
+	ErrMissingID = errors.New("A preview ID is required")
+)
+
+// ErrCleanupFailed is returned when setting up a new preview fails and deleting it fails too.
+// The app is left without an expiry label, so Reap won't delete it.
+type ErrCleanupFailed struct {
+	App        string
+	Err        error
+	CleanupErr error
+}
+
+func (e ErrCleanupFailed) Error() string {
+	return fmt.Sprintf("Setting up preview %s failed: %v. Deleting it also failed: %v", e.App, e.Err, e.CleanupErr)
+}
+
+// ErrInvalidExpiry is returned when a preview's expiry label can't be parsed.
+type ErrInvalidExpiry struct {
+	App   string
+	Value string
+}
+
+func (e ErrInvalidExpiry) Error() string {
+	return fmt.Sprintf("Preview %s has an invalid expiry %q", e.App, e.Value)
+}
+
+// Options controls how a preview is created.
+type Options struct {
+	// Template is the app whose config is copied.
+	Template string
+	// ID identifies the preview, such as a pull request number, and is required. The app is
+	// named after the template and ID. See Name.
+	ID string
+	// Owner is recorded as the preview's owner.
+	Owner string
+	// TTL is how long the preview lives. If zero, DefaultTTL is used.
+	TTL time.Duration
+	// Keys are the config keys copied from the template. If nil, all config values are copied.
+	Keys []string
+	// Image and Procfile are deployed to the preview, if an image is given.
+	Image    string
+	Procfile map[string]string
+	// Now is the time the preview is created. If zero, the current time is used.
+	Now time.Time
+}
+
+// Preview is a preview app.
+type Preview struct {
+	App      string    `json:"app"`
+	Owner    string    `json:"owner"`
+	Template string    `json:"template"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Expired returns true if the preview expired at or before now.
+func (p Preview) Expired(now time.Time) bool {
+	return !p.Expires.After(now)
+}
+
+var invalidName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Name returns the app name of a preview, such as "example-go-pr-42" for the template
+// "example-go" and ID "pr-42". Invalid characters are replaced with hyphens and the name is
+// shortened to fit the controller's limit.
+func Name(template, id string) string {
+	name := invalidName.ReplaceAllString(strings.ToLower(template+"-"+id), "-")
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	return strings.Trim(name, "-")
+}
+
+// Create creates a preview app from a template app. If any step after creating the app fails,
+// the app is deleted. If deleting it fails too, an ErrCleanupFailed is returned.
+func Create(c *deis.Client, opts Options) (Preview, error) {
+	if opts.Template == "" {
+		return Preview{}, ErrMissingTemplate
+	}
+	if Name(opts.Template, opts.ID) == Name(opts.Template, "") {
+		return Preview{}, ErrMissingID
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	preview := Preview{
+		App:      Name(opts.Template, opts.ID),
+		Owner:    opts.Owner,
+		Template: opts.Template,
+		Expires:  opts.Now.Add(opts.TTL).UTC().Truncate(time.Second),
+	}
+
+	values, err := templateValues(c, opts.Template, opts.Keys)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	if _, err := apps.New(c, preview.App); err != nil && !deis.IsErrAPIMismatch(err) {
+		return Preview{}, err
+	}
+
+	if err := setup(c, preview, values, opts); err != nil {
+		if deleteErr := apps.Delete(c, preview.App); deleteErr != nil && !deis.IsErrAPIMismatch(deleteErr) {
+			return Preview{}, ErrCleanupFailed{App: preview.App, Err: err, CleanupErr: deleteErr}
+		}
+		return Preview{}, err
+	}
+
+	return preview, nil
+}
+
+// setup labels a new preview, copies its config and deploys its build.
+func setup(c *deis.Client, preview Preview, values map[string]interface{}, opts Options) error {
+	settings := api.AppSettings{Label: api.Labels{
+		OwnerLabel:    preview.Owner,
+		TemplateLabel: preview.Template,
+		ExpiresLabel:  preview.Expires.Format(time.RFC3339),
+	}}
+	if _, err := appsettings.Set(c, preview.App, settings); err != nil && !deis.IsErrAPIMismatch(err) {
+		return err
+	}
+
+	if len(values) > 0 {
+		if _, err := config.Set(c, preview.App, api.Config{Values: values}); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+
+	if opts.Image != "" {
+		if _, err := builds.New(c, preview.App, opts.Image, opts.Procfile); err != nil && !deis.IsErrAPIMismatch(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateValues retrieves the config values of the template app, limited to keys if given.
+func templateValues(c *deis.Client, template string, keys []string) (map[string]interface{}, error) {
+	cfg, err := config.List(c, template)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+
+	if keys == nil {
+		return cfg.Values, nil
+	}
+
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		if value, ok := cfg.Values[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// List lists the preview apps visible to the user. Apps whose settings can't be read or whose
+// preview labels are invalid are left out.
+func List(c *deis.Client) ([]Preview, error) {
+	previews, _, err := list(c)
+	return previews, err
+}
+
+// list returns the previews, and the apps whose settings couldn't be read or whose preview
+// labels are invalid.
+func list(c *deis.Client) ([]Preview, []Failure, error) {
+	all, _, err := apps.List(c, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, nil, err
+	}
+
+	var previews []Preview
+	var failures []Failure
+	for _, app := range all {
+		settings, err := appsettings.List(c, app.ID)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			failures = append(failures, Failure{Preview: Preview{App: app.ID}, Err: err})
+			continue
+		}
+
+		value, ok := settings.Label[ExpiresLabel]
+		if !ok {
+			continue
+		}
+
+		preview := Preview{App: app.ID, Owner: label(settings, OwnerLabel), Template: label(settings, TemplateLabel)}
+		s, _ := value.(string)
+		if preview.Expires, err = time.Parse(time.RFC3339, s); err != nil {
+			failures = append(failures, Failure{Preview: preview, Err: ErrInvalidExpiry{App: app.ID, Value: fmt.Sprint(value)}})
+			continue
+		}
+		previews = append(previews, preview)
+	}
+
+	return previews, failures, nil
+}
+
+func label(settings api.AppSettings, key string) string {
+	s, _ := settings.Label[key].(string)
+	return s
+}
+
+// ReapOptions controls how expired previews are reaped.
+type ReapOptions struct {
+	// DryRun reports the previews that would be removed without removing them.
+	DryRun bool
+	// Now is the time expiry is checked against. If zero, the current time is used.
+	Now time.Time
+}
+
+// Failure is a preview that couldn't be reaped.
+type Failure struct {
+	Preview Preview
+	Err     error
+}
+
+// Report describes a reaping.
+type Report struct {
+	DryRun bool
+	// Removed are the expired previews, which were deleted unless this was a dry run.
+	Removed []Preview
+	// Kept are the previews that haven't expired.
+	Kept []Preview
+	// Failed are the previews that couldn't be checked or deleted.
+	Failed []Failure
+}
+
+// String renders the Report in a readable format.
+func (r Report) String() string {
+	var b bytes.Buffer
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+
+	for _, p := range r.Removed {
+		fmt.Fprintf(&b, "%s %s (owner %s, expired %s)\n", verb, p.App, p.Owner, p.Expires.Format(time.RFC3339))
+	}
+	for _, f := range r.Failed {
+		fmt.Fprintf(&b, "failed %s: %v\n", f.Preview.App, f.Err)
+	}
+	fmt.Fprintf(&b, "%d %s, %d kept, %d failed\n", len(r.Removed), verb, len(r.Kept), len(r.Failed))
+	return b.String()
+}
+
+// Reap deletes expired previews and reports what was removed. Deleting continues past
+// failures, which are included in the report.
+func Reap(c *deis.Client, opts ReapOptions) (Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	previews, failures, err := list(c)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{DryRun: opts.DryRun, Failed: failures}
+	for _, p := range previews {
+		if !p.Expired(opts.Now) {
+			report.Kept = append(report.Kept, p)
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := apps.Delete(c, p.App); err != nil && !deis.IsErrAPIMismatch(err) {
+				report.Failed = append(report.Failed, Failure{Preview: p, Err: err})
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, p)
+	}
+
+	return report, nil
+}
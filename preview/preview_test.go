@@ -0,0 +1,303 @@
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+var now = time.Date(2016, 2, 13, 12, 0, 0, 0, time.UTC)
+
+// fakeHTTPServer keeps each app's labels and records every change. Deploying the image
+// "broken" fails, and so do reading the settings of the unreadable app and deleting the
+// undeletable app.
+type fakeHTTPServer struct {
+	mu          sync.Mutex
+	labels      map[string]api.Labels
+	unreadable  string
+	undeletable string
+	requests    []string
+}
+
+func newFakeHTTPServer() *fakeHTTPServer {
+	return &fakeHTTPServer{labels: map[string]api.Labels{
+		"example-go": {},
+		"example-go-pr-1": {
+			OwnerLabel:    "alice",
+			TemplateLabel: "example-go",
+			ExpiresLabel:  "2016-02-12T00:00:00Z",
+		},
+		"example-go-pr-2": {
+			OwnerLabel:    "bob",
+			TemplateLabel: "example-go",
+			ExpiresLabel:  "2016-02-14T00:00:00Z",
+		},
+		"example-go-pr-3": {ExpiresLabel: "tomorrow"},
+	}}
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+	if req.Method != "GET" {
+		f.requests = append(f.requests, strings.TrimSpace(fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body)))
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	switch {
+	case req.URL.Path == "/v2/apps/" && req.Method == "GET":
+		var names []string
+		for name := range f.labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var results []string
+		for _, name := range names {
+			results = append(results, fmt.Sprintf(`{"id": "%s"}`, name))
+		}
+		res.Write([]byte(fmt.Sprintf(`{"count": %d, "results": [%s]}`, len(results), strings.Join(results, ","))))
+	case req.URL.Path == "/v2/apps/" && req.Method == "POST":
+		create := api.AppCreateRequest{}
+		json.Unmarshal(body, &create)
+		f.labels[create.ID] = api.Labels{}
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(fmt.Sprintf(`{"id": "%s"}`, create.ID)))
+	case len(parts) == 3 && req.Method == "DELETE":
+		if parts[2] == f.undeletable {
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		delete(f.labels, parts[2])
+		res.WriteHeader(http.StatusNoContent)
+		res.Write(nil)
+	case len(parts) == 4 && parts[3] == "settings" && req.Method == "GET":
+		if parts[2] == f.unreadable {
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		out, _ := json.Marshal(api.AppSettings{Label: f.labels[parts[2]]})
+		res.Write(out)
+	case len(parts) == 4 && parts[3] == "settings" && req.Method == "POST":
+		settings := api.AppSettings{}
+		json.Unmarshal(body, &settings)
+		for key, value := range settings.Label {
+			f.labels[parts[2]][key] = value
+		}
+		res.Write(body)
+	case req.URL.Path == "/v2/apps/example-go/config/" && req.Method == "GET":
+		res.Write([]byte(`{"values": {"DATABASE_URL": "postgres://db", "DEBUG": "true"}}`))
+	case len(parts) == 4 && parts[3] == "config" && req.Method == "POST":
+		res.WriteHeader(http.StatusCreated)
+		res.Write(body)
+	case len(parts) == 4 && parts[3] == "builds" && req.Method == "POST":
+		if strings.Contains(string(body), `"broken"`) {
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(`{}`))
+	default:
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+	}
+}
+
+func TestName(t *testing.T) {
+	t.Parallel()
+
+	if actual := Name("example-go", "PR #42"); actual != "example-go-pr-42" {
+		t.Errorf("Expected example-go-pr-42, Got %s", actual)
+	}
+
+	if actual := Name(strings.Repeat("a", 70), "1"); len(actual) != 63 {
+		t.Errorf("Expected a name of 63 characters, Got %d", len(actual))
+	}
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := Create(deis, Options{
+		Template: "example-go",
+		ID:       "pr-42",
+		Owner:    "alice",
+		TTL:      24 * time.Hour,
+		Keys:     []string{"DATABASE_URL"},
+		Image:    "deis/example-go:pr-42",
+		Now:      now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Preview{
+		App:      "example-go-pr-42",
+		Owner:    "alice",
+		Template: "example-go",
+		Expires:  time.Date(2016, 2, 14, 12, 0, 0, 0, time.UTC),
+	}
+	if preview != expected {
+		t.Errorf("Expected %v, Got %v", expected, preview)
+	}
+
+	expectedRequests := []string{
+		`POST /v2/apps/ {"id":"example-go-pr-42"}`,
+		`POST /v2/apps/example-go-pr-42/settings/ {"label":{"preview-expires":"2016-02-14T12:00:00Z","preview-owner":"alice","preview-template":"example-go"}}`,
+		`POST /v2/apps/example-go-pr-42/config/ {"values":{"DATABASE_URL":"postgres://db"}}`,
+		`POST /v2/apps/example-go-pr-42/builds/ {"image":"deis/example-go:pr-42"}`,
+	}
+	if !reflect.DeepEqual(expectedRequests, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expectedRequests, handler.requests)
+	}
+}
+
+func TestCreateCleansUp(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Create(deis, Options{Template: "example-go", ID: "43", Image: "broken"}); err == nil {
+		t.Fatal("Expected the deploy to fail")
+	}
+
+	if _, ok := handler.labels["example-go-43"]; ok {
+		t.Error("Expected example-go-43 to be deleted")
+	}
+
+	handler.mu.Lock()
+	handler.undeletable = "example-go-44"
+	handler.mu.Unlock()
+	_, err = Create(deis, Options{Template: "example-go", ID: "44", Image: "broken"})
+	if failure, ok := err.(ErrCleanupFailed); !ok || failure.App != "example-go-44" {
+		t.Errorf("Expected ErrCleanupFailed for example-go-44, Got %v", err)
+	}
+
+	if _, err := Create(deis, Options{}); err != ErrMissingTemplate {
+		t.Errorf("Expected %v, Got %v", ErrMissingTemplate, err)
+	}
+	for _, id := range []string{"", "--"} {
+		if _, err := Create(deis, Options{Template: "example-go", ID: id}); err != ErrMissingID {
+			t.Errorf("Expected %v for %q, Got %v", ErrMissingID, id, err)
+		}
+	}
+}
+
+func TestReap(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Reap(deis, ReapOptions{DryRun: true, Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `would remove example-go-pr-1 (owner alice, expired 2016-02-12T00:00:00Z)
+failed example-go-pr-3: Preview example-go-pr-3 has an invalid expiry "tomorrow"
+1 would remove, 1 kept, 1 failed
+`
+	if report.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, report.String())
+	}
+
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no changes in a dry run, Got %v", handler.requests)
+	}
+
+	report, err = Reap(deis, ReapOptions{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Removed) != 1 || report.Removed[0].App != "example-go-pr-1" {
+		t.Errorf("Expected example-go-pr-1 to be removed, Got %v", report.Removed)
+	}
+
+	if !reflect.DeepEqual([]string{"DELETE /v2/apps/example-go-pr-1/"}, handler.requests) {
+		t.Errorf("Expected only example-go-pr-1 to be deleted, Got %v", handler.requests)
+	}
+
+	previews, err := List(deis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 1 || previews[0].App != "example-go-pr-2" {
+		t.Errorf("Expected example-go-pr-2 to remain, Got %v", previews)
+	}
+}
+
+func TestReapUnreadable(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	handler.labels["example-go-pr-0"] = api.Labels{}
+	handler.unreadable = "example-go-pr-0"
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Reap(deis, ReapOptions{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Removed) != 1 || report.Removed[0].App != "example-go-pr-1" {
+		t.Errorf("Expected example-go-pr-1 to be removed, Got %v", report.Removed)
+	}
+	if len(report.Failed) != 2 || report.Failed[0].Preview.App != "example-go-pr-0" {
+		t.Errorf("Expected example-go-pr-0 and example-go-pr-3 to fail, Got %v", report.Failed)
+	}
+}
@@ -0,0 +1,151 @@
+// Package procfile provides methods for parsing, validating and writing Procfiles.
+//
+// A Procfile declares an app's process types, one per line:
+//
+//    # Comments and blank lines are ignored.
+//    web: example-go
+//    worker: example-go work
+//
+// Process type names must be lowercase letters, numbers and single hyphens, such as "web" or
+// "queue-worker", the same rule the controller enforces.
+package procfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deis/controller-sdk-go/api"
+)
+
+// maxTypeLength is the longest process type name the controller accepts.
+const maxTypeLength = 63
+
+var typePattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// ErrEmpty is returned when a Procfile declares no process types.
+var ErrEmpty = errors.New("The Procfile declares no process types")
+
+// ErrSyntax is returned when a line of a Procfile is invalid. Line is numbered from 1.
+type ErrSyntax struct {
+	Line   int
+	Reason string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf("Procfile line %d: %s", e.Line, e.Reason)
+}
+
+// ErrInvalidType is returned when a process type name breaks the controller's naming rules.
+type ErrInvalidType struct {
+	Type string
+}
+
+func (e ErrInvalidType) Error() string {
+	return fmt.Sprintf("Invalid process type %q: process types must be lowercase letters, numbers "+
+		"and single hyphens, at most %d characters", e.Type, maxTypeLength)
+}
+
+// ErrEmptyCommand is returned when a process type has no command.
+type ErrEmptyCommand struct {
+	Type string
+}
+
+func (e ErrEmptyCommand) Error() string {
+	return fmt.Sprintf("The process type %s has no command", e.Type)
+}
+
+// Parse parses a Procfile.
+func Parse(data []byte) (api.ProcessType, error) {
+	return Read(bytes.NewReader(data))
+}
+
+// Read reads and parses a Procfile.
+func Read(r io.Reader) (api.ProcessType, error) {
+	procfile := api.ProcessType{}
+	lines := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i == -1 {
+			return nil, ErrSyntax{Line: number, Reason: fmt.Sprintf("expected \"<type>: <command>\", got %q", line)}
+		}
+		name, command := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+
+		if err := ValidateType(name); err != nil {
+			return nil, ErrSyntax{Line: number, Reason: err.Error()}
+		}
+		if command == "" {
+			return nil, ErrSyntax{Line: number, Reason: ErrEmptyCommand{Type: name}.Error()}
+		}
+		if first, ok := lines[name]; ok {
+			return nil, ErrSyntax{Line: number, Reason: fmt.Sprintf(
+				"duplicate process type %s, first declared on line %d", name, first)}
+		}
+
+		lines[name] = number
+		procfile[name] = command
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(procfile) == 0 {
+		return nil, ErrEmpty
+	}
+	return procfile, nil
+}
+
+// ValidateType returns an ErrInvalidType if a process type name breaks the controller's
+// naming rules.
+func ValidateType(name string) error {
+	if len(name) > maxTypeLength || !typePattern.MatchString(name) {
+		return ErrInvalidType{Type: name}
+	}
+	return nil
+}
+
+// Validate checks every process type name and command of a procfile map, such as the one
+// passed to builds.New. Process types are checked in alphabetical order.
+func Validate(procfile map[string]string) error {
+	for _, name := range types(procfile) {
+		if err := ValidateType(name); err != nil {
+			return err
+		}
+		if strings.TrimSpace(procfile[name]) == "" {
+			return ErrEmptyCommand{Type: name}
+		}
+	}
+	return nil
+}
+
+// Format writes a procfile map in the Procfile format, sorted by process type.
+func Format(procfile map[string]string) []byte {
+	var b bytes.Buffer
+	for _, name := range types(procfile) {
+		fmt.Fprintf(&b, "%s: %s\n", name, procfile[name])
+	}
+	return b.Bytes()
+}
+
+func types(procfile map[string]string) []string {
+	names := make([]string, 0, len(procfile))
+	for name := range procfile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
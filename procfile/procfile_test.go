@@ -0,0 +1,108 @@
+package procfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deis/controller-sdk-go/api"
+)
+
+const procfileFixture = `# Processes for example-go
+web: example-go
+
+worker:example-go work --queue=default
+	queue-2 :  example-go work --queue=slow
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	actual, err := Parse([]byte(procfileFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := api.ProcessType{
+		"web":     "example-go",
+		"worker":  "example-go work --queue=default",
+		"queue-2": "example-go work --queue=slow",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	checks := []struct {
+		procfile string
+		expected error
+	}{
+		{"web: a\nworker b\n", ErrSyntax{Line: 2, Reason: `expected "<type>: <command>", got "worker b"`}},
+		{"# web\n\nWeb: a\n", ErrSyntax{Line: 3, Reason: ErrInvalidType{Type: "Web"}.Error()}},
+		{"web_1: a\n", ErrSyntax{Line: 1, Reason: ErrInvalidType{Type: "web_1"}.Error()}},
+		{"web:\n", ErrSyntax{Line: 1, Reason: "The process type web has no command"}},
+		{"web: a\r\n\r\nweb: b\r\n", ErrSyntax{Line: 3, Reason: "duplicate process type web, first declared on line 1"}},
+		{"# nothing\n", ErrEmpty},
+	}
+
+	for _, check := range checks {
+		if _, err := Parse([]byte(check.procfile)); err != check.expected {
+			t.Errorf("Expected %v, Got %v", check.expected, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []string{"web", "worker-2", "a", strings.Repeat("a", 63)}
+	for _, name := range valid {
+		if err := ValidateType(name); err != nil {
+			t.Errorf("Expected %s to be valid, Got %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "Web", "web_1", "-web", "web-", "web--1", "web.1", strings.Repeat("a", 64)}
+	for _, name := range invalid {
+		if err := ValidateType(name); err != (ErrInvalidType{Type: name}) {
+			t.Errorf("Expected %s to be invalid, Got %v", name, err)
+		}
+	}
+
+	if err := Validate(map[string]string{"web": "a", "worker": " "}); err != (ErrEmptyCommand{Type: "worker"}) {
+		t.Errorf("Expected %v, Got %v", ErrEmptyCommand{Type: "worker"}, err)
+	}
+
+	if err := Validate(nil); err != nil {
+		t.Errorf("Expected no error, Got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	procfile, err := Parse([]byte(procfileFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `queue-2: example-go work --queue=slow
+web: example-go
+worker: example-go work --queue=default
+`
+	actual := Format(procfile)
+	if string(actual) != expected {
+		t.Errorf("Expected %s, Got %s", expected, actual)
+	}
+
+	roundTrip, err := Parse(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(procfile, roundTrip) {
+		t.Errorf("Expected %v, Got %v", procfile, roundTrip)
+	}
+}
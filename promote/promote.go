@@ -0,0 +1,199 @@
+// Package promote provides methods for promoting a build from one app to another, such as
+// from staging to production.
+//
+// Promote deploys the source app's current image and procfile to the target app, optionally
+// copying selected config keys first, and waits for the target's new release to roll out.
+// Plan shows what a promotion would change without changing anything.
+package promote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/releasediff"
+	"github.com/deis/controller-sdk-go/rollout"
+	"github.com/deis/controller-sdk-go/sensitive"
+)
+
+// ErrNoBuild is returned when the source app has never been built.
+var ErrNoBuild = errors.New("The source app has no build to promote")
+
+// ErrMissingKey is returned when a config key to sync is not set in the source app.
+type ErrMissingKey struct {
+	Key string
+}
+
+func (e ErrMissingKey) Error() string {
+	return fmt.Sprintf("Config key %s is not set in the source app", e.Key)
+}
+
+// Options controls how a build is promoted.
+type Options struct {
+	// Keys are config keys copied from the source app to the target app before deploying.
+	Keys []string
+	// Wait controls how the target's release is waited on.
+	Wait rollout.Options
+}
+
+// Plan describes what promoting a build will change in the target app.
+type Plan struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// SourceVersion is the source app's release being promoted.
+	SourceVersion int `json:"source_version"`
+	// Image and Procfile are the build deployed to the target.
+	Image    string            `json:"image"`
+	Procfile map[string]string `json:"procfile"`
+	// Build are the changes to the target's image and procfile.
+	Build []releasediff.Change `json:"build,omitempty"`
+	// Config are the changes to the target's synced config keys.
+	Config []releasediff.Change `json:"config,omitempty"`
+
+	values map[string]interface{}
+}
+
+// Empty returns true if the promotion would not change the target.
+func (p Plan) Empty() bool {
+	return len(p.Build) == 0 && len(p.Config) == 0
+}
+
+// String renders the Plan as a readable report, with sensitive config values masked.
+func (p Plan) String() string {
+	return p.Render(sensitive.Masker{})
+}
+
+// Render renders the Plan as a readable report, masking config values with m.
+func (p Plan) Render(m sensitive.Masker) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== promote %s v%d to %s\n", p.Source, p.SourceVersion, p.Target)
+	if p.Empty() {
+		b.WriteString("no changes\n")
+		return b.String()
+	}
+
+	for _, section := range []struct {
+		name    string
+		changes []releasediff.Change
+	}{{"build", p.Build}, {"config", releasediff.MaskChanges(m, p.Config)}} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", section.name)
+		for _, change := range section.changes {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+	return b.String()
+}
+
+// Result describes a promotion.
+type Result struct {
+	Plan
+	// TargetVersion is the target app's release running the promoted build.
+	TargetVersion int `json:"target_version"`
+}
+
+// NewPlan compares the source app's current build and synced config keys to the target app.
+// An app's current build is the build of its latest release, not necessarily its newest build.
+func NewPlan(c *deis.Client, source, target string, opts Options) (Plan, error) {
+	plan := Plan{Source: source, Target: target}
+
+	// The build of the latest release is the one running, which after a rollback is older
+	// than the newest build.
+	sourceBuild, sourceRelease, err := releasediff.CurrentBuild(c, source)
+	if err != nil {
+		return Plan{}, err
+	}
+	if sourceBuild.UUID == "" {
+		return Plan{}, ErrNoBuild
+	}
+	plan.Image, plan.Procfile = sourceBuild.Image, sourceBuild.Procfile
+	plan.SourceVersion = sourceRelease.Version
+
+	targetBuild, _, err := releasediff.CurrentBuild(c, target)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan.Build = releasediff.DiffMaps(buildMap(targetBuild), buildMap(sourceBuild))
+
+	if len(opts.Keys) == 0 {
+		return plan, nil
+	}
+
+	sourceConfig, err := config.List(c, source)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Plan{}, err
+	}
+	targetConfig, err := config.List(c, target)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Plan{}, err
+	}
+
+	before, after := map[string]string{}, map[string]string{}
+	plan.values = map[string]interface{}{}
+	for _, key := range opts.Keys {
+		value, ok := sourceConfig.Values[key]
+		if !ok {
+			return Plan{}, ErrMissingKey{Key: key}
+		}
+		after[key] = fmt.Sprint(value)
+
+		if current, ok := targetConfig.Values[key]; ok {
+			before[key] = fmt.Sprint(current)
+			if reflect.DeepEqual(current, value) {
+				continue
+			}
+		}
+		plan.values[key] = value
+	}
+	plan.Config = releasediff.DiffMaps(before, after)
+
+	return plan, nil
+}
+
+// Promote deploys the source app's current build to the target app and waits for the
+// target's release to roll out. Synced config keys are set before the build is deployed, so
+// the promoted build never runs with stale config. If the target already runs the build and
+// config, nothing is deployed and the target's current release is verified.
+func Promote(ctx context.Context, c *deis.Client, source, target string, opts Options) (Result, error) {
+	plan, err := NewPlan(c, source, target, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(plan.values) > 0 {
+		_, err := config.Set(c, target, api.Config{Values: plan.values})
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Result{Plan: plan}, err
+		}
+	}
+
+	if len(plan.Build) > 0 {
+		_, err := builds.New(c, target, plan.Image, plan.Procfile)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Result{Plan: plan}, err
+		}
+	}
+
+	progress, err := rollout.Wait(ctx, c, target, -1, opts.Wait)
+	return Result{Plan: plan, TargetVersion: progress.Version}, err
+}
+
+// buildMap flattens a build for comparison, keying procfile entries by "procfile.<type>".
+func buildMap(b api.Build) map[string]string {
+	out := map[string]string{}
+	if b.Image != "" {
+		out["image"] = b.Image
+	}
+	for procType, command := range b.Procfile {
+		out["procfile."+procType] = command
+	}
+	return out
+}
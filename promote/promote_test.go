@@ -0,0 +1,242 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/releasediff"
+	"github.com/deis/controller-sdk-go/rollout"
+)
+
+const buildsFixture string = `
+{
+    "count": 1,
+    "next": null,
+    "previous": null,
+    "results": [%s]
+}`
+
+var buildFixtures = map[string]string{
+	"staging": `{
+        "app": "staging",
+        "image": "deis/example-go:v2",
+        "procfile": {"web": "example-go", "worker": "example-go work"},
+        "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"
+    }`,
+	"production": `{
+        "app": "production",
+        "image": "deis/example-go:v1",
+        "procfile": {"web": "example-go"},
+        "uuid": "a6c2b2a7-1f4f-4a2c-9a4c-6f4e6b2c1d3e"
+    }`,
+	// rolled-back was rolled back to the build before its newest one.
+	"rolled-back": `{
+        "app": "rolled-back",
+        "image": "deis/example-go:v3",
+        "procfile": {"web": "example-go"},
+        "uuid": "0c9f1e2d-7b3a-4e5f-8a6b-9c0d1e2f3a4b"
+    }, {
+        "app": "rolled-back",
+        "image": "deis/example-go:v2",
+        "procfile": {"web": "example-go", "worker": "example-go work"},
+        "uuid": "5e8d7c6b-3a2f-4b1e-9d0c-8b7a6f5e4d3c"
+    }`,
+	"empty": ``,
+}
+
+// releaseFixtures are the latest release of each app except production.
+var releaseFixtures = map[string]string{
+	"staging":     `{"app": "staging", "version": 7, "build": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"}`,
+	"rolled-back": `{"app": "rolled-back", "version": 9, "build": "5e8d7c6b-3a2f-4b1e-9d0c-8b7a6f5e4d3c"}`,
+	"empty":       `{"app": "empty", "version": 1}`,
+}
+
+var configs = map[string]string{
+	"staging":    `{"values": {"FOO": "bar", "WORKERS": 4, "SAME": "x"}}`,
+	"production": `{"values": {"FOO": "old", "SAME": "x"}}`,
+}
+
+// fakeHTTPServer creates a new production release for every change and serves production's
+// pods as running its latest release.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	latest   int
+	requests []string
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for app, build := range buildFixtures {
+		if req.URL.Path == "/v2/apps/"+app+"/builds/" && req.Method == "GET" {
+			res.Write([]byte(fmt.Sprintf(buildsFixture, build)))
+			return
+		}
+		if req.URL.Path == "/v2/apps/"+app+"/releases/" && req.Method == "GET" && app != "production" {
+			res.Write([]byte(fmt.Sprintf(`{"count": 1, "results": [%s]}`, releaseFixtures[app])))
+			return
+		}
+		if req.URL.Path == "/v2/apps/"+app+"/config/" && req.Method == "GET" {
+			res.Write([]byte(configs[app]))
+			return
+		}
+	}
+
+	switch {
+	case req.URL.Path == "/v2/apps/production/releases/" && req.Method == "GET":
+		res.Write([]byte(fmt.Sprintf(`{"count": 1, "results": [{"app": "production", "version": %d, "build": "a6c2b2a7-1f4f-4a2c-9a4c-6f4e6b2c1d3e"}]}`, f.latest)))
+	case req.URL.Path == "/v2/apps/production/pods/" && req.Method == "GET":
+		res.Write([]byte(fmt.Sprintf(`{"count": 1, "results": [{"release": "v%d", "type": "web", "name": "web-1", "state": "up", "started": "2016-02-13T00:47:52"}]}`, f.latest)))
+	case req.Method == "POST" && (req.URL.Path == "/v2/apps/production/config/" || req.URL.Path == "/v2/apps/production/builds/"):
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fmt.Println(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		f.requests = append(f.requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body))
+		f.latest++
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(`{}`))
+	default:
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{latest: 10}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := NewPlan(deis, "staging", "production", Options{Keys: []string{"FOO", "WORKERS", "SAME"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBuild := []releasediff.Change{
+		{Key: "image", Action: releasediff.Changed, Old: "deis/example-go:v1", New: "deis/example-go:v2"},
+		{Key: "procfile.worker", Action: releasediff.Added, New: "example-go work"},
+	}
+	if !reflect.DeepEqual(expectedBuild, plan.Build) {
+		t.Errorf("Expected %v, Got %v", expectedBuild, plan.Build)
+	}
+
+	expected := `=== promote staging v7 to production
+build:
+  ~ image: deis/example-go:v1 -> deis/example-go:v2
+  + procfile.worker=example-go work
+config:
+  ~ FOO: old -> bar
+  + WORKERS=4
+`
+	if plan.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, plan.String())
+	}
+
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no changes, Got %v", handler.requests)
+	}
+}
+
+func TestNewPlanRolledBack(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(&fakeHTTPServer{latest: 10})
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := NewPlan(deis, "rolled-back", "production", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The latest release runs the build before the newest one, so that's the build promoted.
+	if plan.Image != "deis/example-go:v2" || plan.SourceVersion != 9 {
+		t.Errorf("Expected deis/example-go:v2 of v9, Got %s of v%d", plan.Image, plan.SourceVersion)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{latest: 10}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Promote(context.Background(), deis, "staging", "production", Options{
+		Keys: []string{"FOO", "SAME"},
+		Wait: rollout.Options{Interval: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.SourceVersion != 7 || result.TargetVersion != 12 {
+		t.Errorf("Expected staging v7 to be promoted to production v12, Got v%d to v%d",
+			result.SourceVersion, result.TargetVersion)
+	}
+
+	expected := []string{
+		`POST /v2/apps/production/config/ {"values":{"FOO":"bar"}}`,
+		`POST /v2/apps/production/builds/ {"image":"deis/example-go:v2","procfile":{"web":"example-go","worker":"example-go work"}}`,
+	}
+	if !reflect.DeepEqual(expected, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expected, handler.requests)
+	}
+}
+
+func TestPromoteErrors(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{latest: 10}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Promote(context.Background(), deis, "staging", "production", Options{Keys: []string{"MISSING"}})
+	if expected := (ErrMissingKey{Key: "MISSING"}); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+
+	if _, err = Promote(context.Background(), deis, "empty", "production", Options{}); err != ErrNoBuild {
+		t.Errorf("Expected %v, Got %v", ErrNoBuild, err)
+	}
+
+	if len(handler.requests) != 0 {
+		t.Errorf("Expected no changes, Got %v", handler.requests)
+	}
+}
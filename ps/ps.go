@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 
 	deis "github.com/deis/controller-sdk-go"
 	"github.com/deis/controller-sdk-go/api"
@@ -77,25 +79,20 @@ func Restart(c *deis.Client, appID string, procType string, name string) (api.Po
 // ByType organizes processes of an app by process type.
 func ByType(processes api.PodsList) api.PodTypes {
 	var pts api.PodTypes
+	// index maps process types to their position in pts.
+	index := map[string]int{}
 
 	for _, process := range processes {
-		exists := false
-		// Is processtype for process already exists, append to it.
-		for i, pt := range pts {
-			if pt.Type == process.Type {
-				exists = true
-				pts[i].PodsList = append(pts[i].PodsList, process)
-				break
-			}
-		}
+		i, exists := index[process.Type]
 
 		// Is processtype for process doesn't exist, create a new one
 		if !exists {
-			pts = append(pts, api.PodType{
-				Type:     process.Type,
-				PodsList: api.PodsList{process},
-			})
+			i = len(pts)
+			index[process.Type] = i
+			pts = append(pts, api.PodType{Type: process.Type})
 		}
+
+		pts[i].PodsList = append(pts[i].PodsList, process)
 	}
 
 	// Sort the pods alphabetically by name.
@@ -108,3 +105,46 @@ func ByType(processes api.PodsList) api.PodTypes {
 
 	return pts
 }
+
+// Live returns the processes the app is scaled to. Processes that are terminating or stopped
+// are left out, and so are all but the processes of the newest release of each type, so the
+// old processes of a release that's rolling out aren't included.
+func Live(processes api.PodsList) api.PodsList {
+	newest := map[string]int{}
+	var running api.PodsList
+
+	for _, process := range processes {
+		switch api.ParsePodState(process.State) {
+		case api.PodTerminating, api.PodDown, api.PodDestroyed:
+			continue
+		}
+
+		running = append(running, process)
+		if version := releaseVersion(process); version > newest[process.Type] {
+			newest[process.Type] = version
+		}
+	}
+
+	var live api.PodsList
+	for _, process := range running {
+		if releaseVersion(process) == newest[process.Type] {
+			live = append(live, process)
+		}
+	}
+	return live
+}
+
+// Replicas counts the processes of each type the way the app is scaled, which are the
+// processes Live returns.
+func Replicas(processes api.PodsList) map[string]int {
+	replicas := map[string]int{}
+	for _, process := range Live(processes) {
+		replicas[process.Type]++
+	}
+	return replicas
+}
+
+func releaseVersion(process api.Pods) int {
+	version, _ := strconv.Atoi(strings.TrimPrefix(process.Release, "v"))
+	return version
+}
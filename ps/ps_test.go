@@ -311,3 +311,21 @@ func TestByType(t *testing.T) {
 		t.Errorf("Expected: %v, Got %v", expected, actual)
 	}
 }
+
+func TestReplicas(t *testing.T) {
+	t.Parallel()
+
+	input := api.PodsList{
+		{Type: "web", Name: "web-1", Release: "v2", State: "terminating"},
+		{Type: "web", Name: "web-2", Release: "v2", State: "up"},
+		{Type: "web", Name: "web-3", Release: "v3", State: "up"},
+		{Type: "web", Name: "web-4", Release: "v3", State: "starting"},
+		{Type: "worker", Name: "worker-1", Release: "v2", State: "up"},
+		{Type: "cron", Name: "cron-1", Release: "v3", State: "down"},
+	}
+
+	expected := map[string]int{"web": 2, "worker": 1}
+	if actual := Replicas(input); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
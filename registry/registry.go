@@ -0,0 +1,192 @@
+// Package registry provides methods for managing an app's private docker registry credentials.
+//
+// Credentials can be read from a docker config file, as written by `docker login`, and set on
+// an app. Credentials are only ever printed with the password masked, and errors never include
+// them.
+//
+// This example copies the local docker credentials for an image's registry to an app:
+//
+//    dockerConfig, err := registry.LoadDockerConfig("")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    creds, ok, err := dockerConfig.ForImage("quay.io/deis/example-go:v2")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    if ok {
+//        _, err = registry.Set(client, "example-go", creds)
+//    }
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/imageref"
+)
+
+// dockerHubHosts are the names docker config files use for DefaultRegistry.
+var dockerHubHosts = map[string]bool{
+	"index.docker.io":         true,
+	"registry-1.docker.io":    true,
+	"registry.hub.docker.com": true,
+}
+
+// ErrMissingCredentials is returned when setting credentials without a username or password.
+var ErrMissingCredentials = errors.New("A registry username and password are required")
+
+// ErrInvalidAuth is returned when a docker config auth entry can't be decoded.
+type ErrInvalidAuth struct {
+	Host string
+}
+
+func (e ErrInvalidAuth) Error() string {
+	return fmt.Sprintf("The docker config auth for %s is not base64 encoded \"username:password\"", e.Host)
+}
+
+// DockerConfig is the registry credentials of a docker config file, by registry host.
+// Credentials kept by a credential helper, set with "credsStore" or "credHelpers", are not
+// read.
+type DockerConfig struct {
+	Auths map[string]api.RegistryCredentials
+}
+
+// DefaultDockerConfigPath returns the path of the docker config file, which is in
+// $DOCKER_CONFIG if set, and ~/.docker otherwise.
+func DefaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home := os.Getenv("HOME")
+	if home == "" {
+		home = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadDockerConfig reads a docker config file. If path is empty, DefaultDockerConfigPath is
+// used. A missing file has no credentials.
+func LoadDockerConfig(path string) (DockerConfig, error) {
+	if path == "" {
+		path = DefaultDockerConfigPath()
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return DockerConfig{Auths: map[string]api.RegistryCredentials{}}, nil
+	}
+	if err != nil {
+		return DockerConfig{}, err
+	}
+	defer f.Close()
+
+	return ReadDockerConfig(f)
+}
+
+// ReadDockerConfig parses a docker config file. Each auth entry's username and password come
+// from its base64 "auth" field, or from its "username" and "password" fields.
+func ReadDockerConfig(r io.Reader) (DockerConfig, error) {
+	file := struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}{}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return DockerConfig{}, err
+	}
+
+	dockerConfig := DockerConfig{Auths: map[string]api.RegistryCredentials{}}
+	for key, entry := range file.Auths {
+		host := normalizeHost(key)
+		creds := api.RegistryCredentials{Username: entry.Username, Password: entry.Password}
+
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return DockerConfig{}, ErrInvalidAuth{Host: host}
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return DockerConfig{}, ErrInvalidAuth{Host: host}
+			}
+			creds = api.RegistryCredentials{Username: parts[0], Password: parts[1]}
+		}
+
+		if !creds.Empty() {
+			dockerConfig.Auths[host] = creds
+		}
+	}
+
+	return dockerConfig, nil
+}
+
+// Lookup returns the credentials of a registry host, such as "quay.io" or "localhost:5000".
+func (d DockerConfig) Lookup(host string) (api.RegistryCredentials, bool) {
+	creds, ok := d.Auths[normalizeHost(host)]
+	return creds, ok
+}
+
+// ForImage returns the credentials of an image's registry. Images without a registry use
+// imageref.DefaultRegistry.
+func (d DockerConfig) ForImage(image string) (api.RegistryCredentials, bool, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return api.RegistryCredentials{}, false, err
+	}
+
+	creds, ok := d.Lookup(ref.Normalized().Registry)
+	return creds, ok, nil
+}
+
+// normalizeHost reduces a docker config key, such as "https://index.docker.io/v1/", to its host.
+func normalizeHost(key string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	if i := strings.Index(host, "/"); i != -1 {
+		host = host[:i]
+	}
+	host = strings.ToLower(host)
+
+	if dockerHubHosts[host] {
+		return imageref.DefaultRegistry
+	}
+	return host
+}
+
+// Get returns an app's registry credentials.
+func Get(c *deis.Client, appID string) (api.RegistryCredentials, error) {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.RegistryCredentials{}, err
+	}
+	return cfg.RegistryCredentials(), nil
+}
+
+// Set sets an app's registry credentials and creates a new release.
+func Set(c *deis.Client, appID string, creds api.RegistryCredentials) (api.Config, error) {
+	if creds.Username == "" || creds.Password == "" {
+		return api.Config{}, ErrMissingCredentials
+	}
+
+	return config.SetRegistry(c, appID, map[string]string{
+		"username": creds.Username,
+		"password": creds.Password,
+	})
+}
+
+// Clear removes an app's registry credentials and creates a new release.
+func Clear(c *deis.Client, appID string) (api.Config, error) {
+	return config.UnsetRegistry(c, appID, "username", "password")
+}
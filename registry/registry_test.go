@@ -0,0 +1,185 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+// "deis:secret" and "quay:hunter2", base64 encoded.
+const dockerConfigFixture string = `
+{
+    "auths": {
+        "https://index.docker.io/v1/": {"auth": "ZGVpczpzZWNyZXQ="},
+        "quay.io": {"auth": "cXVheTpodW50ZXIy"},
+        "localhost:5000": {"username": "local", "password": "pass"},
+        "helper.example.com": {}
+    },
+    "credsStore": "osxkeychain"
+}`
+
+func TestReadDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	dockerConfig, err := ReadDockerConfig(strings.NewReader(dockerConfigFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]api.RegistryCredentials{
+		"docker.io":      {Username: "deis", Password: "secret"},
+		"quay.io":        {Username: "quay", Password: "hunter2"},
+		"localhost:5000": {Username: "local", Password: "pass"},
+	}
+	if !reflect.DeepEqual(expected, dockerConfig.Auths) {
+		t.Errorf("Expected %v, Got %v", expected, dockerConfig.Auths)
+	}
+
+	checks := map[string]string{
+		"deis/example-go":                 "deis",
+		"example-go:v2":                   "deis",
+		"docker.io/deis/example-go":       "deis",
+		"quay.io/deis/example-go:v2":      "quay",
+		"localhost:5000/example-go":       "local",
+		"helper.example.com/example-go":   "",
+		"registry.example.com/example-go": "",
+	}
+	for image, username := range checks {
+		creds, ok, err := dockerConfig.ForImage(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (username != "") || creds.Username != username {
+			t.Errorf("%s: Expected %q, Got %q", image, username, creds.Username)
+		}
+	}
+
+	if _, _, err := dockerConfig.ForImage("Example-go"); err == nil {
+		t.Error("Expected an invalid image to fail")
+	}
+}
+
+func TestReadDockerConfigInvalidAuth(t *testing.T) {
+	t.Parallel()
+
+	_, err := ReadDockerConfig(strings.NewReader(`{"auths": {"quay.io": {"auth": "bm9jb2xvbg=="}}}`))
+	if expected := (ErrInvalidAuth{Host: "quay.io"}); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+}
+
+func TestLoadDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(dockerConfigFixture), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerConfig, err := LoadDockerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds, ok := dockerConfig.Lookup("https://quay.io"); !ok || creds.Username != "quay" {
+		t.Errorf("Expected the quay.io credentials, Got %v", creds)
+	}
+
+	dockerConfig, err = LoadDockerConfig(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dockerConfig.Auths) != 0 {
+		t.Errorf("Expected no credentials, Got %v", dockerConfig.Auths)
+	}
+}
+
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.URL.Path != "/v2/apps/example-go/config/" {
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+		return
+	}
+
+	if req.Method == "GET" {
+		res.Write([]byte(`{"registry": {"username": "deis", "password": "secret"}}`))
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+	f.requests = append(f.requests, string(body))
+	res.WriteHeader(http.StatusCreated)
+	res.Write([]byte(`{}`))
+}
+
+func TestSetAndClear(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := Get(d, "example-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (api.RegistryCredentials{Username: "deis", Password: "secret"}); creds != expected {
+		t.Errorf("Expected %v, Got %v", expected, creds)
+	}
+
+	if _, err := Set(d, "example-go", api.RegistryCredentials{Username: "deis"}); err != ErrMissingCredentials {
+		t.Errorf("Expected %v, Got %v", ErrMissingCredentials, err)
+	}
+
+	if _, err := Set(d, "example-go", api.RegistryCredentials{Username: "quay", Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Clear(d, "example-go"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`{"registry":{"password":"hunter2","username":"quay"}}`,
+		`{"registry":{"password":null,"username":null}}`,
+	}
+	if !reflect.DeepEqual(expected, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expected, handler.requests)
+	}
+}
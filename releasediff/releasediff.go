@@ -0,0 +1,364 @@
+// Package releasediff provides comparisons between two releases of an app.
+//
+// A release only records the UUIDs of its build and config. Builds are looked up in the app's
+// build history, but the controller only serves an app's current config, so older configs are
+// resolved through a ConfigResolver. Snapshots can record configs as they are released so
+// later comparisons can resolve them. When a config can't be resolved, the diff still covers
+// the build and notes which config was unavailable.
+package releasediff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/builds"
+	"github.com/deis/controller-sdk-go/config"
+	"github.com/deis/controller-sdk-go/releases"
+	"github.com/deis/controller-sdk-go/sensitive"
+)
+
+// listLimit is the number of builds searched when resolving a release's build.
+const listLimit = 1000
+
+// ErrBuildNotFound is returned when a release's build is not in the app's build history.
+type ErrBuildNotFound struct {
+	UUID string
+}
+
+func (e ErrBuildNotFound) Error() string {
+	return fmt.Sprintf("Build %s was not found", e.UUID)
+}
+
+// ErrConfigUnavailable is returned by a ConfigResolver which can't resolve a config.
+type ErrConfigUnavailable struct {
+	UUID string
+}
+
+func (e ErrConfigUnavailable) Error() string {
+	return fmt.Sprintf("Config %s is not available", e.UUID)
+}
+
+// ConfigResolver looks up an app's config by UUID.
+type ConfigResolver interface {
+	// Config returns the config with the UUID, or an ErrConfigUnavailable.
+	Config(c *deis.Client, appID string, uuid string) (api.Config, error)
+}
+
+// Current resolves an app's current config only.
+type Current struct{}
+
+// Config returns the app's current config if its UUID matches.
+func (Current) Config(c *deis.Client, appID string, uuid string) (api.Config, error) {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.Config{}, err
+	}
+	if cfg.UUID != uuid {
+		return api.Config{}, ErrConfigUnavailable{UUID: uuid}
+	}
+	return cfg, nil
+}
+
+// Snapshots resolves configs from recorded copies, keyed by UUID, falling back to the
+// app's current config.
+type Snapshots map[string]api.Config
+
+// Record records an app's current config.
+func (s Snapshots) Record(c *deis.Client, appID string) error {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return err
+	}
+	s[cfg.UUID] = cfg
+	return nil
+}
+
+// Config returns the recorded config with the UUID, or the app's current config if it matches.
+func (s Snapshots) Config(c *deis.Client, appID string, uuid string) (api.Config, error) {
+	if cfg, ok := s[uuid]; ok {
+		return cfg, nil
+	}
+	return Current{}.Config(c, appID, uuid)
+}
+
+// Action is the kind of a change.
+type Action string
+
+const (
+	// Added is a value which only exists in the newer release.
+	Added Action = "added"
+	// Removed is a value which only exists in the older release.
+	Removed Action = "removed"
+	// Changed is a value which differs between the releases.
+	Changed Action = "changed"
+)
+
+// Change is a difference in a single value between two releases.
+type Change struct {
+	Key    string `json:"key"`
+	Action Action `json:"action"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// String displays the Change in a readable format.
+func (c Change) String() string {
+	switch c.Action {
+	case Added:
+		return fmt.Sprintf("+ %s=%s", c.Key, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s", c.Key)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Key, c.Old, c.New)
+	}
+}
+
+// Diff is the difference between two releases of an app.
+type Diff struct {
+	App  string `json:"app"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	// Image is the change of the build image, if any. Its key is "image".
+	Image *Change `json:"image,omitempty"`
+	// Procfile are the changes to process type commands, keyed by process type.
+	Procfile []Change `json:"procfile,omitempty"`
+	// Env are the changes to environment variables.
+	Env []Change `json:"env,omitempty"`
+	// Memory and CPU are the changes to limits, keyed by process type.
+	Memory []Change `json:"memory,omitempty"`
+	CPU    []Change `json:"cpu,omitempty"`
+	// Healthchecks are the changes to probes, keyed by process type and probe,
+	// such as "web/livenessProbe".
+	Healthchecks []Change `json:"healthchecks,omitempty"`
+	// Unavailable are the UUIDs of configs that couldn't be resolved. If not empty, the
+	// config sections of the diff are empty.
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// Empty returns true if no differences were found.
+func (d Diff) Empty() bool {
+	return d.Image == nil && len(d.Procfile) == 0 && len(d.Env) == 0 && len(d.Memory) == 0 &&
+		len(d.CPU) == 0 && len(d.Healthchecks) == 0
+}
+
+// String renders the Diff as a readable report, with sensitive env values masked.
+func (d Diff) String() string {
+	return d.Render(sensitive.Masker{})
+}
+
+// Render renders the Diff as a readable report, masking env values with m.
+func (d Diff) Render(m sensitive.Masker) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== %s v%d..v%d\n", d.App, d.From, d.To)
+
+	if d.Image != nil {
+		fmt.Fprintf(&b, "image: %s -> %s\n", orNone(d.Image.Old), orNone(d.Image.New))
+	}
+
+	sections := []struct {
+		name    string
+		changes []Change
+	}{
+		{"procfile", d.Procfile},
+		{"env", MaskChanges(m, d.Env)},
+		{"memory", d.Memory},
+		{"cpu", d.CPU},
+		{"healthchecks", d.Healthchecks},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", section.name)
+		for _, change := range section.changes {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+
+	for _, uuid := range d.Unavailable {
+		fmt.Fprintf(&b, "config %s is unavailable; config changes are not shown\n", uuid)
+	}
+
+	if d.Empty() && len(d.Unavailable) == 0 {
+		b.WriteString("no changes\n")
+	}
+
+	return b.String()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// Compare compares two versions of an app. If resolver is nil, Current is used, so only the
+// config of the latest release can be resolved.
+func Compare(c *deis.Client, appID string, from, to int, resolver ConfigResolver) (Diff, error) {
+	if resolver == nil {
+		resolver = Current{}
+	}
+
+	before, err := releases.Get(c, appID, from)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Diff{}, err
+	}
+	after, err := releases.Get(c, appID, to)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Diff{}, err
+	}
+
+	diff := Diff{App: appID, From: from, To: to}
+
+	if before.Build != after.Build {
+		oldBuild, newBuild, err := resolveBuilds(c, appID, before.Build, after.Build)
+		if err != nil {
+			return Diff{}, err
+		}
+		if oldBuild.Image != newBuild.Image {
+			diff.Image = &Change{Key: "image", Action: Changed, Old: oldBuild.Image, New: newBuild.Image}
+		}
+		diff.Procfile = DiffMaps(oldBuild.Procfile, newBuild.Procfile)
+	}
+
+	if before.Config != after.Config {
+		oldConfig, oldErr := resolver.Config(c, appID, before.Config)
+		newConfig, newErr := resolver.Config(c, appID, after.Config)
+		for _, err := range []error{oldErr, newErr} {
+			if unavailable, ok := err.(ErrConfigUnavailable); ok {
+				diff.Unavailable = append(diff.Unavailable, unavailable.UUID)
+			} else if err != nil {
+				return Diff{}, err
+			}
+		}
+
+		if len(diff.Unavailable) == 0 {
+			diff.Env = DiffMaps(displayMap(oldConfig.Values), displayMap(newConfig.Values))
+			diff.Memory = DiffMaps(displayMap(oldConfig.Memory), displayMap(newConfig.Memory))
+			diff.CPU = DiffMaps(displayMap(oldConfig.CPU), displayMap(newConfig.CPU))
+			diff.Healthchecks = DiffMaps(probes(oldConfig.Healthcheck), probes(newConfig.Healthcheck))
+		}
+	}
+
+	return diff, nil
+}
+
+// CurrentBuild retrieves an app's latest release and the build it runs. After a rollback, that
+// build is older than the app's newest build. Both are empty if the app has no releases, and
+// the build is empty if the latest release has none.
+func CurrentBuild(c *deis.Client, appID string) (api.Build, api.Release, error) {
+	rs, _, err := releases.List(c, appID, 1)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.Build{}, api.Release{}, err
+	}
+	if len(rs) == 0 {
+		return api.Build{}, api.Release{}, nil
+	}
+	if rs[0].Build == "" {
+		return api.Build{}, rs[0], nil
+	}
+
+	build, _, err := resolveBuilds(c, appID, rs[0].Build, "")
+	if err != nil {
+		return api.Build{}, api.Release{}, err
+	}
+	return build, rs[0], nil
+}
+
+// resolveBuilds finds two builds by UUID. An empty UUID resolves to an empty build.
+func resolveBuilds(c *deis.Client, appID, oldUUID, newUUID string) (api.Build, api.Build, error) {
+	history, _, err := builds.List(c, appID, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.Build{}, api.Build{}, err
+	}
+
+	found := map[string]api.Build{"": {}}
+	for _, build := range history {
+		found[build.UUID] = build
+	}
+
+	for _, uuid := range []string{oldUUID, newUUID} {
+		if _, ok := found[uuid]; !ok {
+			return api.Build{}, api.Build{}, ErrBuildNotFound{UUID: uuid}
+		}
+	}
+	return found[oldUUID], found[newUUID], nil
+}
+
+// DiffMaps compares two maps and returns their changes, sorted by key.
+func DiffMaps(before, after map[string]string) []Change {
+	var keys []string
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []Change
+	for _, key := range keys {
+		o, inOld := before[key]
+		n, inNew := after[key]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Key: key, Action: Added, New: n})
+		case !inNew:
+			changes = append(changes, Change{Key: key, Action: Removed, Old: o})
+		case o != n:
+			changes = append(changes, Change{Key: key, Action: Changed, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+// MaskChanges returns a copy of config value changes with sensitive values masked by m.
+func MaskChanges(m sensitive.Masker, changes []Change) []Change {
+	masked := make([]Change, len(changes))
+	for i, change := range changes {
+		change.Old, change.New = m.Change(change.Key, change.Old, change.New)
+		masked[i] = change
+	}
+	return masked
+}
+
+// displayMap converts config values to strings, encoding anything that isn't a string as JSON.
+func displayMap(m map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(m))
+	for key, value := range m {
+		out[key] = display(value)
+	}
+	return out
+}
+
+func display(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
+
+// probes flattens healthchecks to JSON, keyed by process type and probe.
+func probes(healthchecks map[string]*api.Healthchecks) map[string]string {
+	out := map[string]string{}
+	for procType, checks := range healthchecks {
+		if checks == nil {
+			continue
+		}
+		for probe, check := range *checks {
+			out[procType+"/"+probe] = display(check)
+		}
+	}
+	return out
+}
@@ -0,0 +1,241 @@
+package releasediff
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/sensitive"
+)
+
+const releaseFixture string = `
+{
+    "app": "example-go",
+    "build": "%s",
+    "config": "%s",
+    "created": "2014-01-01T00:00:00UTC",
+    "owner": "test",
+    "summary": "test changed things",
+    "updated": "2014-01-01T00:00:00UTC",
+    "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75",
+    "version": %d
+}`
+
+const buildsFixture string = `
+{
+    "count": 2,
+    "next": null,
+    "previous": null,
+    "results": [
+        {
+            "app": "example-go",
+            "image": "deis/example-go:v2",
+            "procfile": {"web": "example-go", "worker": "example-go work"},
+            "uuid": "build-2"
+        },
+        {
+            "app": "example-go",
+            "image": "deis/example-go:v1",
+            "procfile": {"web": "example-go --old", "clock": "example-go clock"},
+            "uuid": "build-1"
+        }
+    ]
+}`
+
+const configFixture string = `
+{
+    "owner": "test",
+    "app": "example-go",
+    "values": {"DEBUG": "false", "PORT": 5000, "WORKERS": "4"},
+    "memory": {"web": "1G"},
+    "cpu": {"web": "1000m"},
+    "healthcheck": {"web": {"livenessProbe": {"initialDelaySeconds": 5, "tcpSocket": {"port": 5000}}}},
+    "uuid": "config-2"
+}`
+
+type fakeHTTPServer struct{}
+
+func (fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	if req.Method == "GET" {
+		switch req.URL.Path {
+		case "/v2/apps/example-go/releases/v41/":
+			res.Write([]byte(fmt.Sprintf(releaseFixture, "build-1", "config-1", 41)))
+			return
+		case "/v2/apps/example-go/releases/v45/":
+			res.Write([]byte(fmt.Sprintf(releaseFixture, "build-2", "config-2", 45)))
+			return
+		case "/v2/apps/example-go/releases/v46/":
+			res.Write([]byte(fmt.Sprintf(releaseFixture, "build-3", "config-2", 46)))
+			return
+		case "/v2/apps/example-go/builds/":
+			res.Write([]byte(buildsFixture))
+			return
+		case "/v2/apps/example-go/config/":
+			res.Write([]byte(configFixture))
+			return
+		}
+	}
+
+	fmt.Printf("Unrecognized URL %s\n", req.URL)
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+func oldConfig() api.Config {
+	return api.Config{
+		UUID:   "config-1",
+		Values: map[string]interface{}{"DEBUG": "true", "PORT": 5000, "LEGACY": "1"},
+		Memory: map[string]interface{}{"web": "512M"},
+		CPU:    map[string]interface{}{"web": "1000m"},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := Compare(deis, "example-go", 41, 45, Snapshots{"config-1": oldConfig()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Diff{
+		App:   "example-go",
+		From:  41,
+		To:    45,
+		Image: &Change{Key: "image", Action: Changed, Old: "deis/example-go:v1", New: "deis/example-go:v2"},
+		Procfile: []Change{
+			{Key: "clock", Action: Removed, Old: "example-go clock"},
+			{Key: "web", Action: Changed, Old: "example-go --old", New: "example-go"},
+			{Key: "worker", Action: Added, New: "example-go work"},
+		},
+		Env: []Change{
+			{Key: "DEBUG", Action: Changed, Old: "true", New: "false"},
+			{Key: "LEGACY", Action: Removed, Old: "1"},
+			{Key: "WORKERS", Action: Added, New: "4"},
+		},
+		Memory: []Change{{Key: "web", Action: Changed, Old: "512M", New: "1G"}},
+		Healthchecks: []Change{{
+			Key:    "web/livenessProbe",
+			Action: Added,
+			New:    `{"initialDelaySeconds":5,"timeoutSeconds":0,"periodSeconds":0,"successThreshold":0,"failureThreshold":0,"tcpSocket":{"port":5000}}`,
+		}},
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+
+	report := `=== example-go v41..v45
+image: deis/example-go:v1 -> deis/example-go:v2
+procfile:
+  - clock
+  ~ web: example-go --old -> example-go
+  + worker=example-go work
+env:
+  ~ DEBUG: true -> false
+  - LEGACY
+  + WORKERS=4
+memory:
+  ~ web: 512M -> 1G
+healthchecks:
+  + web/livenessProbe={"initialDelaySeconds":5,"timeoutSeconds":0,"periodSeconds":0,"successThreshold":0,"failureThreshold":0,"tcpSocket":{"port":5000}}
+`
+	if actual.String() != report {
+		t.Errorf("Expected %s, Got %s", report, actual.String())
+	}
+}
+
+func TestCompareUnavailableConfig(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := Compare(deis, "example-go", 41, 45, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Image == nil || len(actual.Procfile) != 3 {
+		t.Errorf("Expected the build to be compared, Got %v", actual)
+	}
+
+	if !reflect.DeepEqual([]string{"config-1"}, actual.Unavailable) || actual.Env != nil {
+		t.Errorf("Expected config-1 to be unavailable, Got %v", actual)
+	}
+}
+
+func TestCompareMissingBuild(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Compare(deis, "example-go", 45, 46, nil)
+	if expected := (ErrBuildNotFound{UUID: "build-3"}); err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+}
+
+func TestDiffEmpty(t *testing.T) {
+	t.Parallel()
+
+	diff := Diff{App: "example-go", From: 1, To: 2}
+	if !diff.Empty() {
+		t.Error("Expected the diff to be empty")
+	}
+
+	if expected := "=== example-go v1..v2\nno changes\n"; diff.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, diff.String())
+	}
+}
+
+func TestDiffMasked(t *testing.T) {
+	t.Parallel()
+
+	diff := Diff{App: "example-go", From: 1, To: 2, Env: []Change{
+		{Key: "DATABASE_PASSWORD", Action: Changed, Old: "hunter2", New: "hunter3"},
+		{Key: "DEBUG", Action: Added, New: "true"},
+	}}
+
+	expected := `=== example-go v1..v2
+env:
+  ~ DATABASE_PASSWORD: ******** -> ********
+  + DEBUG=true
+`
+	if diff.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, diff.String())
+	}
+
+	if revealed := diff.Render(sensitive.Masker{Reveal: true}); !strings.Contains(revealed, "hunter2 -> hunter3") {
+		t.Errorf("Expected the values to be revealed, Got %s", revealed)
+	}
+}
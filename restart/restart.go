@@ -0,0 +1,218 @@
+// Package restart provides rolling restarts of an app's processes.
+//
+// ps.Restart restarts every pod of an app or process type at once, which briefly takes small
+// apps offline. Rolling restarts pods one batch at a time, waiting for the replacements of each
+// batch to come up before moving on, and stops if a replacement fails to become healthy.
+package restart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/ps"
+)
+
+const (
+	// DefaultInterval is the time waited between polls of an app's pods.
+	DefaultInterval = 2 * time.Second
+	// DefaultTimeout is the longest time to wait for a batch's replacements to come up.
+	DefaultTimeout = 5 * time.Minute
+	// DefaultCrashThreshold is the number of consecutive polls a replacement pod can be
+	// crashed or errored before the restart is aborted.
+	DefaultCrashThreshold = 3
+
+	// listLimit is the number of pods requested when polling an app.
+	listLimit = 1000
+)
+
+// ErrTimeout is returned when the replacements of a batch do not come up before the timeout.
+var ErrTimeout = errors.New("Timed out waiting for the restarted pods to be replaced")
+
+// ErrUnhealthy is returned when a replacement pod is stuck in a crashed or error state.
+type ErrUnhealthy struct {
+	Pod api.Pods
+}
+
+func (e ErrUnhealthy) Error() string {
+	return fmt.Sprintf("Replacement pod %s is in state %s", e.Pod.Name, e.Pod.State)
+}
+
+// Options controls how pods are restarted.
+type Options struct {
+	// BatchSize is the number of pods restarted at once. If zero, pods are restarted one by one.
+	BatchSize int
+	// Interval is the time waited between polls. If zero, DefaultInterval is used.
+	Interval time.Duration
+	// Timeout is the longest time to wait for each batch. If zero, DefaultTimeout is used.
+	Timeout time.Duration
+	// CrashThreshold is the number of consecutive polls a replacement can be crashed or
+	// errored before the restart is aborted. If zero, DefaultCrashThreshold is used.
+	CrashThreshold int
+	// Progress is called after every poll while waiting for a batch.
+	Progress func(Progress)
+}
+
+// Progress is the state of a rolling restart at a point in time.
+type Progress struct {
+	// Batch is the number of the batch being restarted, starting at 1.
+	Batch int
+	// Batches is the total number of batches.
+	Batches int
+	// Ready is the number of live pods of the process type which are up.
+	Ready int
+	// Desired is the number of live pods of the process type before the restart. Live pods
+	// are the ones ps.Live returns.
+	Desired int
+}
+
+// Result describes a rolling restart.
+type Result struct {
+	// Restarted are the names of the pods that were restarted, in order.
+	Restarted []string
+	// Batches is the number of batches that completed.
+	Batches int
+}
+
+// Rolling restarts the pods of a process type in batches. After each batch is restarted,
+// it waits until none of the restarted pods remain and every pod of the process type is up
+// before restarting the next batch. Only live pods are restarted and waited on, so terminating
+// pods and the old pods of a release that's rolling out are left alone.
+//
+// If a replacement fails to come up, Rolling stops and returns the pods restarted so far
+// along with an ErrUnhealthy or ErrTimeout.
+func Rolling(ctx context.Context, c *deis.Client, appID string, procType string, opts Options) (Result, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.CrashThreshold <= 0 {
+		opts.CrashThreshold = DefaultCrashThreshold
+	}
+
+	all, err := list(c, appID, procType)
+	if err != nil {
+		return Result{}, err
+	}
+	// Terminating pods and the old pods of a rollout are already going away, and aren't part of
+	// the process type's scale.
+	pods := ps.Live(all)
+	if len(pods) == 0 {
+		return Result{}, deis.ErrPodNotFound
+	}
+
+	desired := len(pods)
+	batches := (desired + opts.BatchSize - 1) / opts.BatchSize
+	result := Result{}
+
+	for batch := 0; batch < batches; batch++ {
+		start := batch * opts.BatchSize
+		end := start + opts.BatchSize
+		if end > desired {
+			end = desired
+		}
+
+		restarted := map[string]bool{}
+		for _, pod := range pods[start:end] {
+			if _, err := ps.Restart(c, appID, procType, pod.Name); err != nil && !deis.IsErrAPIMismatch(err) {
+				return result, err
+			}
+			restarted[pod.Name] = true
+			result.Restarted = append(result.Restarted, pod.Name)
+		}
+
+		progress := Progress{Batch: batch + 1, Batches: batches, Desired: desired}
+		if err := wait(ctx, c, appID, procType, restarted, progress, opts); err != nil {
+			return result, err
+		}
+		result.Batches++
+	}
+
+	return result, nil
+}
+
+// wait waits until the restarted pods are gone and every pod of the process type is up.
+func wait(ctx context.Context, c *deis.Client, appID, procType string, restarted map[string]bool,
+	progress Progress, opts Options) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	crashes := map[string]int{}
+	for {
+		pods, err := list(c, appID, procType)
+		if err != nil {
+			return err
+		}
+
+		replaced := true
+		for _, pod := range pods {
+			if restarted[pod.Name] {
+				replaced = false
+			}
+		}
+
+		progress.Ready = 0
+		for _, pod := range ps.Live(pods) {
+			if restarted[pod.Name] {
+				continue
+			}
+
+			state := api.ParsePodState(pod.State)
+			if state == api.PodUp {
+				progress.Ready++
+			}
+
+			if state.Failed() {
+				crashes[pod.Name]++
+				if crashes[pod.Name] >= opts.CrashThreshold {
+					return ErrUnhealthy{Pod: pod}
+				}
+			} else {
+				delete(crashes, pod.Name)
+			}
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+
+		if replaced && progress.Ready >= progress.Desired {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return ErrTimeout
+			}
+			return ctx.Err()
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// list lists the pods of a process type, sorted by name.
+func list(c *deis.Client, appID, procType string) (api.PodsList, error) {
+	pods, _, err := ps.List(c, appID, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+
+	var out api.PodsList
+	for _, pod := range pods {
+		if pod.Type == procType {
+			out = append(out, pod)
+		}
+	}
+	sort.Sort(out)
+	return out, nil
+}
@@ -0,0 +1,279 @@
+package restart
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+const podFixture string = `
+{
+    "release": "%s",
+    "type": "%s",
+    "name": "%s",
+    "state": "%s",
+    "started": "2016-02-13T00:47:52"
+}`
+
+// fakeHTTPServer simulates an app whose restarted pods are replaced by pods that start up
+// after being listed once. Replacements in the crash-test app crash instead.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	pods     map[string]api.PodsList
+	restarts []string
+	maxDown  int
+}
+
+func newFakeHTTPServer() *fakeHTTPServer {
+	pods := func() api.PodsList {
+		return api.PodsList{
+			{Release: "v2", Type: "web", Name: "web-a", State: "up"},
+			{Release: "v2", Type: "web", Name: "web-b", State: "up"},
+			{Release: "v2", Type: "web", Name: "web-c", State: "up"},
+			{Release: "v2", Type: "worker", Name: "worker-a", State: "up"},
+		}
+	}
+	return &fakeHTTPServer{
+		pods: map[string]api.PodsList{"example-go": pods(), "crash-test": pods()},
+	}
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "v2" || parts[1] != "apps" || parts[3] != "pods" {
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+		return
+	}
+	app := parts[2]
+
+	if req.Method == "GET" {
+		pods := f.pods[app]
+		res.Write([]byte(fmt.Sprintf(`{"count": %d, "results": %s}`, len(pods), render(pods))))
+
+		up := 0
+		for i, pod := range pods {
+			if pod.State == "up" && pod.Type == "web" {
+				up++
+			}
+			if pod.State == "starting" {
+				pods[i].State = "up"
+				if app == "crash-test" {
+					pods[i].State = "crashed"
+				}
+			}
+		}
+		if down := 3 - up; down > f.maxDown {
+			f.maxDown = down
+		}
+		return
+	}
+
+	// POST /v2/apps/<app>/pods/<type>/<name>/restart/
+	if req.Method == "POST" && len(parts) == 7 {
+		name := parts[5]
+		f.restarts = append(f.restarts, name)
+
+		var pods api.PodsList
+		for _, pod := range f.pods[app] {
+			if pod.Name != name {
+				pods = append(pods, pod)
+			}
+		}
+		replacement := api.Pods{Release: "v2", Type: parts[4], Name: name + "-new", State: "starting"}
+		f.pods[app] = append(pods, replacement)
+
+		res.Write([]byte(render(api.PodsList{replacement})))
+		return
+	}
+
+	fmt.Printf("Unrecognized URL %s\n", req.URL)
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+func render(pods api.PodsList) string {
+	var out []string
+	for _, pod := range pods {
+		out = append(out, fmt.Sprintf(podFixture, pod.Release, pod.Type, pod.Name, pod.State))
+	}
+	return "[" + strings.Join(out, ",") + "]"
+}
+
+func TestRolling(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batches []int
+	opts := Options{
+		Interval: time.Millisecond,
+		Progress: func(p Progress) {
+			if len(batches) == 0 || batches[len(batches)-1] != p.Batch {
+				batches = append(batches, p.Batch)
+			}
+		},
+	}
+
+	result, err := Rolling(context.Background(), deis, "example-go", "web", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Result{Restarted: []string{"web-a", "web-b", "web-c"}, Batches: 3}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, Got %v", expected, result)
+	}
+
+	if !reflect.DeepEqual([]int{1, 2, 3}, batches) {
+		t.Errorf("Expected progress for batches [1 2 3], Got %v", batches)
+	}
+
+	if handler.maxDown > 1 {
+		t.Errorf("Expected at most 1 pod to be down at once, Got %d", handler.maxDown)
+	}
+}
+
+func TestRollingLivePods(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	// A terminating pod and a pod of the previous release are neither restarted nor waited on.
+	handler.pods["rolling-out"] = api.PodsList{
+		{Release: "v1", Type: "web", Name: "web-old", State: "up"},
+		{Release: "v2", Type: "web", Name: "web-a", State: "up"},
+		{Release: "v2", Type: "web", Name: "web-b", State: "up"},
+		{Release: "v2", Type: "web", Name: "web-c", State: "terminating"},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desired := 0
+	opts := Options{
+		Interval: time.Millisecond,
+		Timeout:  time.Second,
+		Progress: func(p Progress) { desired = p.Desired },
+	}
+
+	result, err := Rolling(context.Background(), deis, "rolling-out", "web", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Result{Restarted: []string{"web-a", "web-b"}, Batches: 2}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, Got %v", expected, result)
+	}
+	if desired != 2 {
+		t.Errorf("Expected 2 desired pods, Got %d", desired)
+	}
+}
+
+func TestRollingBatches(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Rolling(context.Background(), deis, "example-go", "web", Options{
+		BatchSize: 2,
+		Interval:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Batches != 2 {
+		t.Errorf("Expected 2 batches, Got %d", result.Batches)
+	}
+
+	if handler.maxDown != 2 {
+		t.Errorf("Expected 2 pods to be down at once, Got %d", handler.maxDown)
+	}
+}
+
+func TestRollingMissingType(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Rolling(context.Background(), d, "example-go", "clock", Options{}); err != deis.ErrPodNotFound {
+		t.Errorf("Expected %v, Got %v", deis.ErrPodNotFound, err)
+	}
+}
+
+func TestRollingUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Rolling(context.Background(), deis, "crash-test", "web", Options{
+		Interval:       time.Millisecond,
+		CrashThreshold: 2,
+	})
+
+	unhealthy, ok := err.(ErrUnhealthy)
+	if !ok {
+		t.Fatalf("Expected ErrUnhealthy, Got %v", err)
+	}
+
+	if unhealthy.Pod.Name != "web-a-new" {
+		t.Errorf("Expected web-a-new to be unhealthy, Got %s", unhealthy.Pod.Name)
+	}
+
+	expected := Result{Restarted: []string{"web-a"}}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, Got %v", expected, result)
+	}
+
+	if !reflect.DeepEqual([]string{"web-a"}, handler.restarts) {
+		t.Errorf("Expected only web-a to be restarted, Got %v", handler.restarts)
+	}
+}
@@ -0,0 +1,136 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/releases"
+)
+
+// DefaultRestoreTimeout is the longest time to wait for a failed rollback to be undone when
+// no timeout is given.
+const DefaultRestoreTimeout = 5 * time.Minute
+
+// ErrNoBuild is returned when rolling back to a release that has no build to run.
+var ErrNoBuild = errors.New("The release has no build")
+
+// ErrRollbackFailed is returned when a rollback did not roll out or failed a health check.
+// The app was rolled forward to the release it was running before the rollback.
+type ErrRollbackFailed struct {
+	// Version is the release the app was rolled back to.
+	Version int
+	// Err is the reason the rollback failed.
+	Err error
+	// Restored is the release created when rolling forward. It is -1 if it couldn't be created.
+	Restored int
+	// RestoreErr is the error rolling forward, if any.
+	RestoreErr error
+}
+
+func (e ErrRollbackFailed) Error() string {
+	if e.RestoreErr != nil {
+		return fmt.Sprintf("Rollback to v%d failed: %v. Restoring the previous release also failed: %v",
+			e.Version, e.Err, e.RestoreErr)
+	}
+	return fmt.Sprintf("Rollback to v%d failed: %v. The previous release was restored as v%d",
+		e.Version, e.Err, e.Restored)
+}
+
+// Check is a health check run once a rollback has rolled out.
+type Check func(ctx context.Context) error
+
+// RollbackOptions controls how a rollback is verified.
+type RollbackOptions struct {
+	// Options controls how the rollback, and rolling forward on failure, are waited on.
+	Options
+	// Checks are run in order once the rollback has rolled out. Any error fails the rollback.
+	Checks []Check
+}
+
+// RollbackResult describes a successful rollback.
+type RollbackResult struct {
+	// Previous is the release that was running before the rollback.
+	Previous int
+	// Target is the release that was rolled back to.
+	Target int
+	// Version is the release created by the rollback.
+	Version int
+}
+
+// Rollback rolls an app back to a release and verifies it. If version is -1, the app is
+// rolled back to the release before the latest one.
+//
+// The target must exist, be older than the latest release and have a build. Once the rollback
+// is created, Rollback waits for it to roll out and runs the checks. If either fails, the app
+// is rolled forward to the release it was running before and an ErrRollbackFailed is returned.
+func Rollback(ctx context.Context, c *deis.Client, appID string, version int, opts RollbackOptions) (RollbackResult, error) {
+	previous, err := latestVersion(c, appID)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+
+	if version == -1 {
+		version = previous - 1
+	}
+	if version < 1 || version >= previous {
+		return RollbackResult{}, deis.ErrInvalidVersion
+	}
+
+	target, err := releases.Get(c, appID, version)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return RollbackResult{}, err
+	}
+	if target.Build == "" {
+		return RollbackResult{}, ErrNoBuild
+	}
+
+	created, err := releases.Rollback(c, appID, version)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return RollbackResult{}, err
+	}
+
+	result := RollbackResult{Previous: previous, Target: version, Version: created}
+	if err := verify(ctx, c, appID, created, opts); err != nil {
+		return result, restore(c, appID, version, previous, err, opts.Options)
+	}
+
+	return result, nil
+}
+
+// verify waits for a release to roll out and runs the checks.
+func verify(ctx context.Context, c *deis.Client, appID string, version int, opts RollbackOptions) error {
+	if _, err := Wait(ctx, c, appID, version, opts.Options); err != nil {
+		return err
+	}
+
+	for _, check := range opts.Checks {
+		if err := check(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore rolls an app forward to the release it ran before a failed rollback. It doesn't use
+// the caller's context, since that may be why the rollback failed.
+func restore(c *deis.Client, appID string, version, previous int, cause error, opts Options) error {
+	failure := ErrRollbackFailed{Version: version, Err: cause, Restored: -1}
+
+	restored, err := releases.Rollback(c, appID, previous)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		failure.RestoreErr = err
+		return failure
+	}
+	failure.Restored = restored
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultRestoreTimeout
+	}
+	if _, err := Wait(context.Background(), c, appID, restored, opts); err != nil {
+		failure.RestoreErr = err
+	}
+	return failure
+}
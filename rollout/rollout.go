@@ -0,0 +1,222 @@
+// Package rollout provides methods for waiting on new app releases to be rolled out.
+//
+// Deploying a build, changing config, scaling or rolling back returns as soon as the controller
+// creates the new release, before its pods are running. Wait polls an app's pods until every
+// pod is running the release, a pod fails, or the wait times out.
+//
+// This example waits for a config change to roll out:
+//
+//    _, err := config.Set(client, "example-go", api.Config{Values: values})
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    _, err = rollout.Wait(context.Background(), client, "example-go", -1, rollout.Options{
+//        Timeout: 5 * time.Minute,
+//    })
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/ps"
+	"github.com/deis/controller-sdk-go/releases"
+)
+
+const (
+	// DefaultInterval is the time waited between polls of an app's pods.
+	DefaultInterval = 2 * time.Second
+	// DefaultCrashThreshold is the number of consecutive polls a pod can be crashed or
+	// errored before the rollout fails.
+	DefaultCrashThreshold = 3
+
+	// listLimit is the number of pods requested when polling an app.
+	listLimit = 1000
+)
+
+// ErrTimeout is returned when a release does not roll out before the timeout.
+var ErrTimeout = errors.New("Timed out waiting for the release to roll out")
+
+// ErrPodFailed is returned when a pod of the release is stuck in a crashed or error state.
+type ErrPodFailed struct {
+	Pod api.Pods
+}
+
+func (e ErrPodFailed) Error() string {
+	return fmt.Sprintf("Pod %s of release %s is in state %s", e.Pod.Name, e.Pod.Release, e.Pod.State)
+}
+
+// Progress is the state of a rollout at a point in time.
+type Progress struct {
+	// Version is the release being rolled out.
+	Version int
+	// Ready is the number of pods of the release which are up.
+	Ready int
+	// Total is the number of pods of the release.
+	Total int
+	// Outdated is the number of pods of other releases which are still running.
+	Outdated int
+	// Expected is the number of pods the release should run, from Options.Replicas or the
+	// app's scale.
+	Expected int
+	// Pods are the app's pods, as last listed.
+	Pods api.PodsList
+}
+
+// Done returns true once every pod is running the release and is up, and at least the
+// expected number of pods are. A release without pods is only done if it's expected to have
+// none, as when the app is scaled to zero; otherwise its pods may not have been created yet.
+func (p Progress) Done() bool {
+	if p.Total == 0 && p.Expected > 0 {
+		return false
+	}
+	return p.Ready == p.Total && p.Ready >= p.Expected && p.Outdated == 0
+}
+
+// Options controls how a rollout is watched.
+type Options struct {
+	// Interval is the time waited between polls. If zero, DefaultInterval is used.
+	Interval time.Duration
+	// Timeout is the longest time to wait for the rollout. If zero, Wait only stops
+	// when the context is done.
+	Timeout time.Duration
+	// CrashThreshold is the number of consecutive polls a pod can be crashed or errored
+	// before the rollout fails. If zero, DefaultCrashThreshold is used.
+	CrashThreshold int
+	// Replicas is the number of pods the release is expected to run. If zero, and a poll finds
+	// no pods of the release, the app's scale is read to tell a release whose pods haven't been
+	// created yet from an app scaled to zero.
+	Replicas int
+	// Progress is called with the state of the rollout after every poll.
+	Progress func(Progress)
+}
+
+// Wait waits until every pod of an app is running the given release version and is up.
+// If version is -1, the app's latest release is waited on. At least opts.Replicas pods, or
+// the app's scale, must be up, so a release whose pods haven't been listed yet isn't
+// mistaken for one that rolled out. An app scaled to zero is done once its old pods are gone.
+//
+// Wait returns ErrTimeout if the timeout is reached, an ErrPodFailed if a pod of the release
+// is stuck crashing, or the context's error if it is cancelled.
+func Wait(ctx context.Context, c *deis.Client, appID string, version int, opts Options) (Progress, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.CrashThreshold <= 0 {
+		opts.CrashThreshold = DefaultCrashThreshold
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	if version == -1 {
+		latest, err := latestVersion(c, appID)
+		if err != nil {
+			return Progress{}, err
+		}
+		version = latest
+	}
+
+	release := fmt.Sprintf("v%d", version)
+	crashes := map[string]int{}
+	expected, scaled := opts.Replicas, opts.Replicas > 0
+
+	for {
+		pods, _, err := ps.List(c, appID, listLimit)
+		if err != nil && !deis.IsErrAPIMismatch(err) {
+			return Progress{}, err
+		}
+
+		progress := Progress{Version: version, Expected: expected, Pods: pods}
+		for _, pod := range pods {
+			if pod.Release != release {
+				progress.Outdated++
+				continue
+			}
+
+			progress.Total++
+			state := api.ParsePodState(pod.State)
+			if state == api.PodUp {
+				progress.Ready++
+			}
+
+			if state.Failed() {
+				crashes[pod.Name]++
+				if crashes[pod.Name] >= opts.CrashThreshold {
+					return progress, ErrPodFailed{Pod: pod}
+				}
+			} else {
+				delete(crashes, pod.Name)
+			}
+		}
+
+		// Without pods of the release, the app's scale tells whether they're still to come.
+		if progress.Total == 0 && !scaled {
+			if expected, err = scale(c, appID); err != nil {
+				return progress, err
+			}
+			scaled = true
+			progress.Expected = expected
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+
+		if progress.Done() {
+			return progress, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded && opts.Timeout > 0 {
+				return progress, ErrTimeout
+			}
+			return progress, ctx.Err()
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// scale retrieves the number of processes an app is scaled to. If the controller doesn't
+// report the app's structure, at least one process is expected.
+func scale(c *deis.Client, appID string) (int, error) {
+	app, err := apps.Get(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return 0, err
+	}
+	if app.Structure == nil {
+		return 1, nil
+	}
+
+	total := 0
+	for _, count := range app.Structure {
+		total += count
+	}
+	return total, nil
+}
+
+// latestVersion retrieves the version of an app's most recent release.
+func latestVersion(c *deis.Client, appID string) (int, error) {
+	rs, _, err := releases.List(c, appID, 1)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return -1, err
+	}
+
+	if len(rs) == 0 {
+		return -1, deis.ErrInvalidVersion
+	}
+
+	// Releases are listed newest first.
+	return rs[0].Version, nil
+}
@@ -0,0 +1,478 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+)
+
+const releasesFixture string = `
+{
+    "count": 1,
+    "next": null,
+    "previous": null,
+    "results": [
+        {
+            "app": "example-go",
+            "version": 3
+        }
+    ]
+}`
+
+const podsFixture string = `
+{
+    "count": %d,
+    "next": null,
+    "previous": null,
+    "results": [%s]
+}`
+
+const podFixture string = `
+{
+    "release": "%s",
+    "type": "web",
+    "name": "%s",
+    "state": "%s",
+    "started": "2016-02-13T00:47:52"
+}`
+
+// pod is a release, name and state.
+type pod [3]string
+
+// fakeHTTPServer serves each app's pod listings in order, repeating the last one, and
+// each app's structure.
+type fakeHTTPServer struct {
+	mu         sync.Mutex
+	pods       map[string][][]pod
+	structures map[string]string
+	polls      map[string]int
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	if req.URL.Path == "/v2/apps/example-go/releases/" && req.Method == "GET" {
+		res.Write([]byte(releasesFixture))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for app, structure := range f.structures {
+		if req.URL.Path == "/v2/apps/"+app+"/" && req.Method == "GET" {
+			res.Write([]byte(fmt.Sprintf(`{"id": "%s", "structure": %s}`, app, structure)))
+			return
+		}
+	}
+
+	for app, listings := range f.pods {
+		if req.URL.Path == "/v2/apps/"+app+"/pods/" && req.Method == "GET" {
+			i := f.polls[app]
+			if i >= len(listings) {
+				i = len(listings) - 1
+			}
+			f.polls[app]++
+
+			results := ""
+			for j, p := range listings[i] {
+				if j > 0 {
+					results += ","
+				}
+				results += fmt.Sprintf(podFixture, p[0], p[1], p[2])
+			}
+			res.Write([]byte(fmt.Sprintf(podsFixture, len(listings[i]), results)))
+			return
+		}
+	}
+
+	fmt.Printf("Unrecognized URL %s\n", req.URL)
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+func newFakeHTTPServer() *fakeHTTPServer {
+	return &fakeHTTPServer{
+		pods: map[string][][]pod{
+			"example-go": {
+				{{"v2", "web-1", "up"}, {"v3", "web-2", "starting"}},
+				{{"v2", "web-1", "terminating"}, {"v3", "web-2", "up"}, {"v3", "web-3", "starting"}},
+				{{"v3", "web-2", "up"}, {"v3", "web-3", "up"}},
+			},
+			"crash-test": {
+				{{"v3", "web-1", "crashed"}},
+				{{"v3", "web-1", "starting"}},
+				{{"v3", "web-1", "crashed"}},
+				{{"v3", "web-1", "crashed"}},
+			},
+			"empty-test": {
+				{},
+				{{"v3", "web-1", "starting"}},
+				{{"v3", "web-1", "up"}},
+			},
+			"stuck-test": {
+				{{"v2", "web-1", "up"}, {"v3", "web-2", "starting"}},
+			},
+			"zero-test": {
+				{{"v2", "web-1", "terminating"}},
+				{},
+			},
+		},
+		structures: map[string]string{
+			"empty-test": `{"web": 1}`,
+			"zero-test":  `{"web": 0}`,
+		},
+		polls: map[string]int{},
+	}
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []Progress
+	opts := Options{
+		Interval: time.Millisecond,
+		Progress: func(p Progress) { progress = append(progress, p) },
+	}
+
+	actual, err := Wait(context.Background(), deis, "example-go", -1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Version != 3 || actual.Ready != 2 || actual.Total != 2 || actual.Outdated != 0 {
+		t.Errorf("Expected 2/2 pods of v3 to be ready, Got %d/%d of v%d with %d outdated",
+			actual.Ready, actual.Total, actual.Version, actual.Outdated)
+	}
+
+	expected := [][3]int{{0, 1, 1}, {1, 2, 1}, {2, 2, 0}}
+	if len(progress) != len(expected) {
+		t.Fatalf("Expected %d progress reports, Got %d", len(expected), len(progress))
+	}
+
+	for i, p := range progress {
+		if (p.Ready != expected[i][0]) || p.Total != expected[i][1] || p.Outdated != expected[i][2] {
+			t.Errorf("Expected ready, total and outdated to be %v, Got %v", expected[i],
+				[3]int{p.Ready, p.Total, p.Outdated})
+		}
+	}
+}
+
+func TestWaitEmpty(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first poll lists no pods, which must not count as a finished rollout.
+	actual, err := Wait(context.Background(), deis, "empty-test", 3, Options{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Ready != 1 || handler.polls["empty-test"] != 3 {
+		t.Errorf("Expected 1 ready pod after 3 polls, Got %d after %d", actual.Ready, handler.polls["empty-test"])
+	}
+
+	if (Progress{Expected: 1}).Done() {
+		t.Error("Expected a rollout without pods not to be done")
+	}
+	if (Progress{Ready: 1, Total: 1, Expected: 2}).Done() {
+		t.Error("Expected a rollout with fewer pods than expected not to be done")
+	}
+}
+
+func TestWaitScaledToZero(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An app scaled to zero runs no pods of its release, so it's done once the old pods are gone.
+	actual, err := Wait(context.Background(), deis, "zero-test", 3, Options{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Total != 0 || actual.Outdated != 0 || handler.polls["zero-test"] != 2 {
+		t.Errorf("Expected no pods after 2 polls, Got %d and %d outdated after %d", actual.Total,
+			actual.Outdated, handler.polls["zero-test"])
+	}
+
+	if !(Progress{}).Done() {
+		t.Error("Expected a rollout expecting no pods to be done without pods")
+	}
+}
+
+func TestWaitCrashed(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Wait(context.Background(), deis, "crash-test", 3, Options{
+		Interval:       time.Millisecond,
+		CrashThreshold: 2,
+	})
+
+	failure, ok := err.(ErrPodFailed)
+	if !ok {
+		t.Fatalf("Expected ErrPodFailed, Got %v", err)
+	}
+
+	if failure.Pod.Name != "web-1" || failure.Pod.State != "crashed" {
+		t.Errorf("Expected web-1 to have crashed, Got %v", failure.Pod)
+	}
+
+	// The first crash recovered, so it should take two more polls to fail.
+	if handler.polls["crash-test"] != 4 {
+		t.Errorf("Expected 4 polls, Got %d", handler.polls["crash-test"])
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Wait(context.Background(), deis, "stuck-test", 3, Options{
+		Interval: time.Millisecond,
+		Timeout:  20 * time.Millisecond,
+	})
+	if err != ErrTimeout {
+		t.Errorf("Expected %v, Got %v", ErrTimeout, err)
+	}
+}
+
+func TestWaitCancelled(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	progress, err := Wait(ctx, deis, "stuck-test", 3, Options{Interval: time.Millisecond})
+	if err != context.Canceled {
+		t.Errorf("Expected %v, Got %v", context.Canceled, err)
+	}
+
+	if progress.Done() {
+		t.Error("Expected the rollout to be incomplete")
+	}
+}
+
+// rollbackServer simulates rollbacks of an app whose latest release is v5. Pods of
+// releases rolled back to v3 crash, and v2 has no build.
+type rollbackServer struct {
+	mu        sync.Mutex
+	latest    int
+	targets   map[int]int
+	rollbacks []int
+}
+
+func newRollbackServer() *rollbackServer {
+	return &rollbackServer{latest: 5, targets: map[int]int{}}
+}
+
+func (f *rollbackServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var version int
+	switch {
+	case req.URL.Path == "/v2/apps/example-go/releases/" && req.Method == "GET":
+		res.Write([]byte(fmt.Sprintf(`{"count": 1, "results": [{"app": "example-go", "version": %d}]}`, f.latest)))
+	case req.URL.Path == "/v2/apps/example-go/releases/rollback/" && req.Method == "POST":
+		body := struct{ Version int }{}
+		json.NewDecoder(req.Body).Decode(&body)
+		f.rollbacks = append(f.rollbacks, body.Version)
+		f.latest++
+		f.targets[f.latest] = body.Version
+		res.Write([]byte(fmt.Sprintf(`{"version": %d}`, f.latest)))
+	case req.Method == "GET" && fmtScan(req.URL.Path, "/v2/apps/example-go/releases/v%d/", &version):
+		build := "build-uuid"
+		if version == 2 {
+			build = ""
+		}
+		res.Write([]byte(fmt.Sprintf(`{"app": "example-go", "version": %d, "build": "%s"}`, version, build)))
+	case req.URL.Path == "/v2/apps/example-go/pods/" && req.Method == "GET":
+		state := "up"
+		if f.targets[f.latest] == 3 {
+			state = "crashed"
+		}
+		pod := fmt.Sprintf(podFixture, fmt.Sprintf("v%d", f.latest), "web-1", state)
+		res.Write([]byte(fmt.Sprintf(podsFixture, 1, pod)))
+	default:
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+	}
+}
+
+func fmtScan(s, format string, v *int) bool {
+	n, err := fmt.Sscanf(s, format, v)
+	return err == nil && n == 1
+}
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	handler := newRollbackServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checked := false
+	result, err := Rollback(context.Background(), deis, "example-go", -1, RollbackOptions{
+		Options: Options{Interval: time.Millisecond},
+		Checks:  []Check{func(context.Context) error { checked = true; return nil }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := RollbackResult{Previous: 5, Target: 4, Version: 6}
+	if result != expected {
+		t.Errorf("Expected %v, Got %v", expected, result)
+	}
+
+	if !checked {
+		t.Error("Expected the health check to run")
+	}
+}
+
+func TestRollbackCheckFailed(t *testing.T) {
+	t.Parallel()
+
+	handler := newRollbackServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unhealthy := errors.New("unhealthy")
+	_, err = Rollback(context.Background(), deis, "example-go", 4, RollbackOptions{
+		Options: Options{Interval: time.Millisecond},
+		Checks:  []Check{func(context.Context) error { return unhealthy }},
+	})
+
+	expected := ErrRollbackFailed{Version: 4, Err: unhealthy, Restored: 7}
+	if err != expected {
+		t.Errorf("Expected %v, Got %v", expected, err)
+	}
+
+	if !reflect.DeepEqual([]int{4, 5}, handler.rollbacks) {
+		t.Errorf("Expected rollbacks to v4 and v5, Got %v", handler.rollbacks)
+	}
+}
+
+func TestRollbackCrashed(t *testing.T) {
+	t.Parallel()
+
+	handler := newRollbackServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Rollback(context.Background(), deis, "example-go", 3, RollbackOptions{
+		Options: Options{Interval: time.Millisecond, CrashThreshold: 1},
+	})
+
+	failure, ok := err.(ErrRollbackFailed)
+	if !ok {
+		t.Fatalf("Expected ErrRollbackFailed, Got %v", err)
+	}
+
+	if _, ok := failure.Err.(ErrPodFailed); !ok || failure.Restored != 7 || failure.RestoreErr != nil {
+		t.Errorf("Expected a pod failure restored as v7, Got %v", failure)
+	}
+}
+
+func TestRollbackInvalid(t *testing.T) {
+	t.Parallel()
+
+	handler := newRollbackServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []int{0, 5, 6} {
+		if _, err := Rollback(context.Background(), d, "example-go", version, RollbackOptions{}); err != deis.ErrInvalidVersion {
+			t.Errorf("Expected %v for v%d, Got %v", deis.ErrInvalidVersion, version, err)
+		}
+	}
+
+	if _, err := Rollback(context.Background(), d, "example-go", 2, RollbackOptions{}); err != ErrNoBuild {
+		t.Errorf("Expected %v, Got %v", ErrNoBuild, err)
+	}
+
+	if len(handler.rollbacks) != 0 {
+		t.Errorf("Expected no rollbacks, Got %v", handler.rollbacks)
+	}
+}
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxYears is how far ahead Next searches before concluding a schedule never fires,
+// such as "0 0 30 2 *".
+const maxYears = 5
+
+// ErrInvalidSchedule is returned when a cron expression can't be parsed.
+type ErrInvalidSchedule struct {
+	Expr   string
+	Reason string
+}
+
+func (e ErrInvalidSchedule) Error() string {
+	return fmt.Sprintf("Invalid schedule %q: %s", e.Expr, e.Reason)
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+type field struct {
+	name     string
+	min, max int
+	// names are the names of the values starting at min, if any.
+	names []string
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: dayNames},
+}
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	expr string
+	// minute, hour, dom, month and dow are bitsets of the values each field matches.
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are true if the day fields start with "*", such as "*" or "*/2". As in
+	// Vixie cron, if neither day field starts with "*", a day matches if either does.
+	domAny, dowAny bool
+}
+
+// Parse parses a standard five field cron expression: minute, hour, day of month, month and
+// day of week. Fields accept "*", values, ranges ("1-5"), steps ("*/15", "0-30/10") and
+// comma-separated lists of those. Months and days of the week can be given by their
+// three-letter names, and Sunday is either 0 or 7. The macros @yearly, @monthly, @weekly,
+// @daily and @hourly are also accepted.
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, ErrInvalidSchedule{expr, fmt.Sprintf("expected %d fields, got %d", len(fields), len(parts))}
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, ErrInvalidSchedule{expr, err.Error()}
+		}
+		sets[i] = set
+	}
+
+	// Sunday can be written as 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &Schedule{
+		expr:   expr,
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(spec, ",") {
+		rangeSpec, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, item)
+			}
+			rangeSpec, step = item[:i], n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangeSpec == "*":
+		case strings.Contains(rangeSpec, "-"):
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if low, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range in %s field %q", f.name, item)
+			}
+		default:
+			v, err := parseValue(rangeSpec, f)
+			if err != nil {
+				return 0, err
+			}
+			low, high = v, v
+			// "5/15" means every 15 starting at 5.
+			if step > 1 {
+				high = f.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	for i, name := range f.names {
+		if strings.ToLower(s) == name {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	return v, nil
+}
+
+// String returns the cron expression the Schedule was parsed from.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t that matches the schedule, in t's location.
+// It returns the zero time if the schedule never matches.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + maxYears
+
+	for t.Year() <= limit {
+		y, m, d := t.Date()
+		switch {
+		case s.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(y, m, d, t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domAny && !s.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,354 @@
+// Package scheduler provides an in-process scheduler for running SDK actions on cron schedules.
+//
+// A Scheduler runs Jobs, each pairing a cron expression with an Action such as scaling a
+// process type or running a command in an app. A job is skipped if its previous run hasn't
+// finished. Every run is logged and reported as a Result. Actions share the client, so only
+// one runs at a time; a long command delays other jobs that come due.
+//
+// This example scales an app up during business hours:
+//
+//    s, err := scheduler.New(client, []scheduler.Job{
+//        {Name: "open", Schedule: "0 9 * * mon-fri", Action: scheduler.Scale{App: "example-go", Type: "web", Replicas: 4}},
+//        {Name: "close", Schedule: "0 18 * * mon-fri", Action: scheduler.Scale{App: "example-go", Type: "web", Replicas: 1}},
+//    }, scheduler.Options{})
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    s.Run(ctx)
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/apps"
+	"github.com/deis/controller-sdk-go/ps"
+)
+
+// ErrNoJobs is returned when a scheduler is created without jobs.
+var ErrNoJobs = errors.New("No jobs were given")
+
+// ErrDuplicateJob is returned when two jobs have the same name.
+type ErrDuplicateJob struct {
+	Name string
+}
+
+func (e ErrDuplicateJob) Error() string {
+	return fmt.Sprintf("Job %s is defined more than once", e.Name)
+}
+
+// ErrCommandFailed is returned when a command run by a Run action exits with a non-zero code.
+type ErrCommandFailed struct {
+	Code   int
+	Output string
+}
+
+func (e ErrCommandFailed) Error() string {
+	return fmt.Sprintf("Command exited with code %d", e.Code)
+}
+
+// Action is an operation run by a job.
+type Action interface {
+	// Do runs the action and returns its output.
+	Do(c *deis.Client) (string, error)
+	// String describes the action.
+	String() string
+}
+
+// Scale is an action which scales a process type of an app.
+type Scale struct {
+	App      string
+	Type     string
+	Replicas int
+}
+
+// Do scales the process type.
+func (s Scale) Do(c *deis.Client) (string, error) {
+	err := ps.Scale(c, s.App, map[string]int{s.Type: s.Replicas})
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return "", err
+	}
+	return fmt.Sprintf("scaled to %d", s.Replicas), nil
+}
+
+func (s Scale) String() string {
+	return fmt.Sprintf("scale %s %s=%d", s.App, s.Type, s.Replicas)
+}
+
+// Run is an action which runs a command in an app.
+type Run struct {
+	App     string
+	Command string
+}
+
+// Do runs the command. A non-zero exit code is returned as an ErrCommandFailed.
+func (r Run) Do(c *deis.Client) (string, error) {
+	res, err := apps.Run(c, r.App, r.Command)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return "", err
+	}
+	if res.ReturnCode != 0 {
+		return res.Output, ErrCommandFailed{Code: res.ReturnCode, Output: res.Output}
+	}
+	return res.Output, nil
+}
+
+func (r Run) String() string {
+	return fmt.Sprintf("run %q in %s", r.Command, r.App)
+}
+
+// Job is an action run on a cron schedule.
+type Job struct {
+	// Name identifies the job in logs and results.
+	Name string
+	// Schedule is a cron expression. See Parse.
+	Schedule string
+	Action   Action
+}
+
+// Clock tells the time and waits. It can be replaced in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Options controls how jobs are run.
+type Options struct {
+	// Clock is the source of time. If nil, the system clock is used.
+	Clock Clock
+	// Logger logs the outcome of every run. If nil, nothing is logged.
+	Logger *log.Logger
+	// Report is called with the outcome of every run.
+	Report func(Result)
+}
+
+// Result is the outcome of a scheduled run.
+type Result struct {
+	Job string
+	// Time is when the run was scheduled.
+	Time time.Time
+	// Duration is how long the action took.
+	Duration time.Duration
+	// Output is the outcome of the action, such as the output of a command. It isn't logged.
+	Output string
+	Err    error
+	// Skipped is true if the run didn't happen because the previous run was still going.
+	Skipped bool
+}
+
+// Execution is an upcoming run of a job.
+type Execution struct {
+	Job    string
+	Time   time.Time
+	Action Action
+}
+
+func (e Execution) String() string {
+	return fmt.Sprintf("%s %s: %s", e.Time.Format(time.RFC3339), e.Job, e.Action)
+}
+
+type entry struct {
+	job      Job
+	schedule *Schedule
+	next     time.Time
+	running  bool
+}
+
+// Scheduler runs jobs on their schedules.
+type Scheduler struct {
+	c    *deis.Client
+	opts Options
+	// clientMu serializes actions, since a deis.Client can't be used concurrently.
+	clientMu sync.Mutex
+	mu       sync.Mutex
+	entries  []*entry
+	wg       sync.WaitGroup
+}
+
+// New creates a Scheduler, parsing the schedule of every job.
+func New(c *deis.Client, jobs []Job, opts Options) (*Scheduler, error) {
+	if len(jobs) == 0 {
+		return nil, ErrNoJobs
+	}
+	if opts.Clock == nil {
+		opts.Clock = realClock{}
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	s := &Scheduler{c: c, opts: opts}
+	names := map[string]bool{}
+	for _, job := range jobs {
+		if names[job.Name] {
+			return nil, ErrDuplicateJob{Name: job.Name}
+		}
+		names[job.Name] = true
+
+		schedule, err := Parse(job.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		s.entries = append(s.entries, &entry{job: job, schedule: schedule})
+	}
+
+	return s, nil
+}
+
+// Preview returns the next n executions after from across all jobs, in order. Nothing is run.
+func (s *Scheduler) Preview(from time.Time, n int) []Execution {
+	next := make([]time.Time, len(s.entries))
+	for i, e := range s.entries {
+		next[i] = e.schedule.Next(from)
+	}
+
+	var executions []Execution
+	for len(executions) < n {
+		first := -1
+		for i, t := range next {
+			if !t.IsZero() && (first < 0 || t.Before(next[first])) {
+				first = i
+			}
+		}
+		if first < 0 {
+			break
+		}
+
+		e := s.entries[first]
+		executions = append(executions, Execution{Job: e.job.Name, Time: next[first], Action: e.job.Action})
+		next[first] = e.schedule.Next(next[first])
+	}
+
+	return executions
+}
+
+// Run runs jobs as they come due until the context is done, then waits for running
+// actions to finish. It returns the context's error.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer s.wg.Wait()
+
+	s.mu.Lock()
+	now := s.opts.Clock.Now()
+	for _, e := range s.entries {
+		e.next = e.schedule.Next(now)
+	}
+	s.mu.Unlock()
+
+	for {
+		next := s.next()
+		if next.IsZero() {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.opts.Clock.After(next.Sub(s.opts.Clock.Now())):
+			s.runDue(s.opts.Clock.Now())
+		}
+	}
+}
+
+// next returns the time the next job is due, or the zero time if none ever will be.
+func (s *Scheduler) next() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, e := range s.entries {
+		if !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+			next = e.next
+		}
+	}
+	return next
+}
+
+// runDue starts every job due at or before now, skipping jobs which are still running.
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+
+	var due []*entry
+	for _, e := range s.entries {
+		if !e.next.IsZero() && !e.next.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Sort(byNext(due))
+
+	var skipped []Result
+	for _, e := range due {
+		scheduled := e.next
+		e.next = e.schedule.Next(now)
+
+		if e.running {
+			skipped = append(skipped, Result{Job: e.job.Name, Time: scheduled, Skipped: true})
+			continue
+		}
+
+		e.running = true
+		s.wg.Add(1)
+		go s.do(e, scheduled)
+	}
+	s.mu.Unlock()
+
+	for _, r := range skipped {
+		s.report(r)
+	}
+}
+
+func (s *Scheduler) do(e *entry, scheduled time.Time) {
+	defer s.wg.Done()
+
+	s.clientMu.Lock()
+	start := s.opts.Clock.Now()
+	output, err := e.job.Action.Do(s.c)
+	s.clientMu.Unlock()
+
+	result := Result{
+		Job:      e.job.Name,
+		Time:     scheduled,
+		Duration: s.opts.Clock.Now().Sub(start),
+		Output:   output,
+		Err:      err,
+	}
+
+	s.mu.Lock()
+	e.running = false
+	s.mu.Unlock()
+
+	s.report(result)
+}
+
+func (s *Scheduler) report(r Result) {
+	switch {
+	case r.Skipped:
+		s.opts.Logger.Printf("%s: skipped run at %s, the previous run is still in progress", r.Job, r.Time.Format(time.RFC3339))
+	case r.Err != nil:
+		s.opts.Logger.Printf("%s: failed after %s: %v", r.Job, r.Duration, r.Err)
+	default:
+		s.opts.Logger.Printf("%s: succeeded in %s", r.Job, r.Duration)
+	}
+
+	if s.opts.Report != nil {
+		s.opts.Report(r)
+	}
+}
+
+type byNext []*entry
+
+func (e byNext) Len() int           { return len(e) }
+func (e byNext) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byNext) Less(i, j int) bool { return e[i].next.Before(e[j].next) }
@@ -0,0 +1,345 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+)
+
+// fakeHTTPServer records scale and run requests. Runs block until release is closed.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	requests []string
+	release  chan struct{}
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body))
+	f.mu.Unlock()
+
+	if req.URL.Path == "/v2/apps/example-go/scale/" && req.Method == "POST" {
+		res.WriteHeader(http.StatusNoContent)
+		res.Write(nil)
+		return
+	}
+
+	if req.URL.Path == "/v2/apps/example-go/run" && req.Method == "POST" {
+		if f.release != nil {
+			<-f.release
+		}
+		res.Write([]byte(`{"output": "done", "exit_code": 0}`))
+		return
+	}
+
+	if req.URL.Path == "/v2/apps/failing-go/run" && req.Method == "POST" {
+		res.Write([]byte(`{"output": "oops", "exit_code": 1}`))
+		return
+	}
+
+	fmt.Printf("Unrecognized URL %s\n", req.URL)
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// fakeClock only moves when advanced.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	}
+	return ch
+}
+
+// Advance waits for the scheduler to start waiting, then moves the clock forward.
+func (c *fakeClock) Advance(t *testing.T, d time.Duration) {
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		if len(c.waiters) > 0 {
+			break
+		}
+		c.mu.Unlock()
+		if time.Since(start) > time.Second {
+			t.Fatal("Timed out waiting for the scheduler to wait")
+		}
+	}
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	var waiters []waiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	c.waiters = waiters
+}
+
+func receive(t *testing.T, results <-chan Result) Result {
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a result")
+		return Result{}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2016, 2, 13, 0, 0, 30, 0, time.UTC)}
+	results := make(chan Result, 10)
+	var logs bytes.Buffer
+
+	s, err := New(deis, []Job{
+		{Name: "scale", Schedule: "* * * * *", Action: Scale{App: "example-go", Type: "web", Replicas: 2}},
+		{Name: "task", Schedule: "*/2 * * * *", Action: Run{App: "example-go", Command: "rake task"}},
+		{Name: "failing", Schedule: "2 * * * *", Action: Run{App: "failing-go", Command: "false"}},
+	}, Options{
+		Clock:  clock,
+		Logger: log.New(&logs, "", 0),
+		Report: func(r Result) { results <- r },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	clock.Advance(t, 30*time.Second)
+	if r := receive(t, results); r.Job != "scale" || r.Err != nil || r.Output != "scaled to 2" {
+		t.Errorf("Expected scale to succeed, Got %v", r)
+	}
+
+	clock.Advance(t, time.Minute)
+	jobs := map[string]Result{}
+	for i := 0; i < 3; i++ {
+		r := receive(t, results)
+		jobs[r.Job] = r
+	}
+
+	if r := jobs["task"]; r.Err != nil || r.Output != "done" || !r.Time.Equal(clock.Now()) {
+		t.Errorf("Expected task to succeed at %v, Got %v", clock.Now(), r)
+	}
+	if err, ok := jobs["failing"].Err.(ErrCommandFailed); !ok || err.Code != 1 || err.Output != "oops" {
+		t.Errorf("Expected failing to exit with code 1, Got %v", jobs["failing"].Err)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected %v, Got %v", context.Canceled, err)
+	}
+
+	if !strings.Contains(logs.String(), "failing: failed after 0s: Command exited with code 1") {
+		t.Errorf("Expected the failure to be logged, Got %s", logs.String())
+	}
+}
+
+func TestRunSkipsOverlapping(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{release: make(chan struct{})}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2016, 2, 13, 0, 0, 0, 0, time.UTC)}
+	results := make(chan Result, 10)
+
+	s, err := New(deis, []Job{
+		{Name: "task", Schedule: "* * * * *", Action: Run{App: "example-go", Command: "rake task"}},
+	}, Options{Clock: clock, Report: func(r Result) { results <- r }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	clock.Advance(t, time.Minute)
+	clock.Advance(t, time.Minute)
+
+	if r := receive(t, results); !r.Skipped || !r.Time.Equal(clock.Now()) {
+		t.Errorf("Expected the run at %v to be skipped, Got %v", clock.Now(), r)
+	}
+
+	close(handler.release)
+	if r := receive(t, results); r.Skipped || r.Err != nil {
+		t.Errorf("Expected the first run to succeed, Got %v", r)
+	}
+
+	cancel()
+	<-done
+
+	if len(handler.requests) != 1 {
+		t.Errorf("Expected 1 run, Got %v", handler.requests)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(nil, []Job{
+		{Name: "open", Schedule: "0 9 * * mon-fri", Action: Scale{App: "example-go", Type: "web", Replicas: 4}},
+		{Name: "close", Schedule: "0 18 * * mon-fri", Action: Scale{App: "example-go", Type: "web", Replicas: 1}},
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2016-02-12 is a Friday.
+	var actual []string
+	for _, e := range s.Preview(time.Date(2016, 2, 12, 12, 0, 0, 0, time.UTC), 4) {
+		actual = append(actual, e.String())
+	}
+
+	expected := []string{
+		"2016-02-12T18:00:00Z close: scale example-go web=1",
+		"2016-02-15T09:00:00Z open: scale example-go web=4",
+		"2016-02-15T18:00:00Z close: scale example-go web=1",
+		"2016-02-16T09:00:00Z open: scale example-go web=4",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(nil, nil, Options{}); err != ErrNoJobs {
+		t.Errorf("Expected %v, Got %v", ErrNoJobs, err)
+	}
+
+	jobs := []Job{{Name: "a", Schedule: "@daily"}, {Name: "a", Schedule: "@daily"}}
+	if _, err := New(nil, jobs, Options{}); err != (ErrDuplicateJob{Name: "a"}) {
+		t.Errorf("Expected %v, Got %v", ErrDuplicateJob{Name: "a"}, err)
+	}
+
+	jobs = []Job{{Name: "a", Schedule: "* * *"}}
+	if _, err := New(nil, jobs, Options{}); err == nil || err.Error() != `Invalid schedule "* * *": expected 5 fields, got 3` {
+		t.Errorf("Expected an invalid schedule, Got %v", err)
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	// 2016-02-13 is a Saturday.
+	from := time.Date(2016, 2, 13, 10, 17, 42, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2016, 2, 13, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2016, 2, 13, 10, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2016, 2, 13, 10, 25, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2016, 2, 13, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2016, 2, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon,WED", time.Date(2016, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * fri", time.Date(2016, 2, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		s, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := s.Next(from); !actual.Equal(test.expected) {
+			t.Errorf("Expected %v for %q, Got %v", test.expected, test.expr, actual)
+		}
+	}
+}
+
+func TestNextDayStep(t *testing.T) {
+	t.Parallel()
+
+	// A day field starting with "*" doesn't restrict the days, so "*/2" and Monday must both
+	// match, as in Vixie cron. 2016-02-16 is a Tuesday, and the 22nd is an even Monday.
+	from := time.Date(2016, 2, 16, 10, 17, 42, 0, time.UTC)
+
+	s, err := Parse("0 0 */2 * 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC)
+	if actual := s.Next(from); !actual.Equal(expected) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@often"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected %q to be invalid", expr)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package sealed
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/config"
+)
+
+// RotateOptions controls how an app's sealed values are rotated.
+type RotateOptions struct {
+	// All re-seals every sealed value, including those already sealed with the primary key.
+	All bool
+	// DryRun reports the values that would be re-sealed without changing the app.
+	DryRun bool
+}
+
+// Rotation describes the rotation of an app's sealed values.
+type Rotation struct {
+	App    string
+	DryRun bool
+	// Primary is the key the values are re-sealed with.
+	Primary string
+	// Resealed are the re-sealed config keys, by the ID of the key they were sealed with.
+	Resealed map[string][]string
+	// Current are the config keys already sealed with the primary key, which were left alone.
+	Current []string
+}
+
+// Count returns the number of re-sealed values.
+func (r Rotation) Count() int {
+	count := 0
+	for _, keys := range r.Resealed {
+		count += len(keys)
+	}
+	return count
+}
+
+// String renders the Rotation in a readable format.
+func (r Rotation) String() string {
+	var b bytes.Buffer
+	verb := "resealed"
+	if r.DryRun {
+		verb = "would reseal"
+	}
+
+	var ids []string
+	for id := range r.Resealed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		for _, key := range r.Resealed[id] {
+			fmt.Fprintf(&b, "%s %s (%s -> %s)\n", verb, key, id, r.Primary)
+		}
+	}
+	fmt.Fprintf(&b, "%d %s, %d current\n", r.Count(), verb, len(r.Current))
+	return b.String()
+}
+
+// Rotate re-seals an app's sealed values with the keyring's primary key in a single release.
+// Every sealed value is opened before anything is changed, so a value sealed with a key
+// missing from the keyring fails the rotation without changing the app.
+//
+// This example rotates to a new key, keeping the old key to open existing values:
+//
+//    next, err := sealed.GenerateKey("2016-02")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    keyring, err := sealed.NewKeyring(next, previous)
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    rotation, err := sealed.Rotate(client, "example-go", keyring, sealed.RotateOptions{})
+func Rotate(c *deis.Client, appID string, r *Keyring, opts RotateOptions) (Rotation, error) {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return Rotation{}, err
+	}
+
+	rotation := Rotation{App: appID, DryRun: opts.DryRun, Primary: r.Primary(), Resealed: map[string][]string{}}
+	values := map[string]string{}
+
+	var keys []string
+	for key := range cfg.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := cfg.Values[key].(string)
+		if !ok || !IsSealed(value) {
+			continue
+		}
+
+		id := KeyID(value)
+		if id == r.Primary() && !opts.All {
+			rotation.Current = append(rotation.Current, key)
+			continue
+		}
+
+		plaintext, err := r.Open(key, value)
+		if err != nil {
+			return Rotation{}, err
+		}
+		if values[key], err = r.Seal(key, plaintext); err != nil {
+			return Rotation{}, err
+		}
+		rotation.Resealed[id] = append(rotation.Resealed[id], key)
+	}
+
+	if opts.DryRun || len(values) == 0 {
+		return rotation, nil
+	}
+
+	if _, err := config.SetValues(c, appID, values); err != nil && !deis.IsErrAPIMismatch(err) {
+		return Rotation{}, err
+	}
+	return rotation, nil
+}
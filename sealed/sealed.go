@@ -0,0 +1,260 @@
+// Package sealed provides client-side encryption of config values.
+//
+// Sealed values are encrypted with AES-256-GCM before they're sent to the controller, so they
+// can't be read through config.List without the key. Each value records the ID of the key that
+// sealed it, so keys can be rotated: a Keyring seals with its primary key and opens values
+// sealed with any of its keys. The config key is authenticated along with the value, so a
+// sealed value can't be copied to another key.
+//
+// A sealed value looks like "sealed:v1:<key id>:<base64 nonce and ciphertext>".
+//
+// This example seals a value, and reads it back:
+//
+//    key, err := sealed.ParseKey(os.Getenv("SEALED_KEY"))
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    keyring, err := sealed.NewKeyring(key)
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    _, err = sealed.Set(client, "example-go", keyring, map[string]string{"API_TOKEN": token})
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    cfg, err := sealed.List(client, "example-go", keyring)
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/config"
+)
+
+const (
+	// Prefix starts every sealed value.
+	Prefix = "sealed:v1:"
+	// KeySize is the length of a key's secret in bytes.
+	KeySize = 32
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+var (
+	// ErrInvalidKey is returned when a key has an invalid ID or secret.
+	ErrInvalidKey = errors.New("A sealing key needs an ID of letters, numbers, '.', '_' or '-' and a 32 byte secret")
+	// ErrNoKeys is returned when creating a keyring without keys.
+	ErrNoKeys = errors.New("A keyring needs at least one key")
+)
+
+// ErrUnknownKey is returned when opening a value sealed with a key that isn't in the keyring.
+type ErrUnknownKey struct {
+	Key string
+	ID  string
+}
+
+func (e ErrUnknownKey) Error() string {
+	return fmt.Sprintf("The value of %s was sealed with the key %s, which is not in the keyring", e.Key, e.ID)
+}
+
+// ErrInvalidValue is returned when a sealed value can't be opened because it's malformed,
+// tampered with or was sealed for another config key.
+type ErrInvalidValue struct {
+	Key string
+}
+
+func (e ErrInvalidValue) Error() string {
+	return fmt.Sprintf("The sealed value of %s is invalid or was sealed for another key", e.Key)
+}
+
+// Key is a sealing key. Printing it with fmt only shows its ID.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// GenerateKey creates a random key.
+func GenerateKey(id string) (Key, error) {
+	key := Key{ID: id, Secret: make([]byte, KeySize)}
+	if _, err := io.ReadFull(rand.Reader, key.Secret); err != nil {
+		return Key{}, err
+	}
+	return key, key.validate()
+}
+
+// ParseKey parses a key encoded with Encode.
+func ParseKey(s string) (Key, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	if len(parts) != 2 {
+		return Key{}, ErrInvalidKey
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Key{}, ErrInvalidKey
+	}
+
+	key := Key{ID: parts[0], Secret: secret}
+	return key, key.validate()
+}
+
+// Encode encodes the key as "<id>:<base64 secret>", for storing it in a file or environment
+// variable.
+func (k Key) Encode() string {
+	return k.ID + ":" + base64.StdEncoding.EncodeToString(k.Secret)
+}
+
+// String displays the key's ID.
+func (k Key) String() string {
+	return k.ID
+}
+
+// GoString displays the key's ID.
+func (k Key) GoString() string {
+	return fmt.Sprintf("sealed.Key{ID:%q}", k.ID)
+}
+
+func (k Key) validate() error {
+	if !keyIDPattern.MatchString(k.ID) || len(k.Secret) != KeySize {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// Keyring seals values with its primary key and opens values sealed with any of its keys.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring whose primary key is the first key. The other keys open values
+// sealed before a rotation.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	r := &Keyring{primary: keys[0].ID, aeads: map[string]cipher.AEAD{}}
+	for _, key := range keys {
+		if err := key.validate(); err != nil {
+			return nil, err
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+		if r.aeads[key.ID], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Primary returns the ID of the key values are sealed with.
+func (r *Keyring) Primary() string {
+	return r.primary
+}
+
+// IsSealed returns true if a value is sealed.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// KeyID returns the ID of the key a value was sealed with, or an empty string if the value
+// isn't sealed.
+func KeyID(value string) string {
+	if !IsSealed(value) {
+		return ""
+	}
+	return strings.SplitN(strings.TrimPrefix(value, Prefix), ":", 2)[0]
+}
+
+// Seal seals the value of a config key with the primary key.
+func (r *Keyring) Seal(key, value string) (string, error) {
+	aead := r.aeads[r.primary]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(key))
+	return Prefix + r.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open opens the sealed value of a config key. Values that aren't sealed are returned as is.
+func (r *Keyring) Open(key, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, Prefix), ":", 2)
+	if len(parts) != 2 {
+		return "", ErrInvalidValue{Key: key}
+	}
+
+	aead, ok := r.aeads[parts[0]]
+	if !ok {
+		return "", ErrUnknownKey{Key: key, ID: parts[0]}
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidValue{Key: key}
+	}
+
+	nonce := sealed[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return "", ErrInvalidValue{Key: key}
+	}
+
+	return string(plaintext), nil
+}
+
+// Set seals values and sets them on an app, creating a new release.
+func Set(c *deis.Client, appID string, r *Keyring, values map[string]string) (api.Config, error) {
+	sealedValues := make(map[string]string, len(values))
+	for key, value := range values {
+		s, err := r.Seal(key, value)
+		if err != nil {
+			return api.Config{}, err
+		}
+		sealedValues[key] = s
+	}
+
+	return config.SetValues(c, appID, sealedValues)
+}
+
+// List retrieves an app's config with its sealed values opened.
+func List(c *deis.Client, appID string, r *Keyring) (api.Config, error) {
+	cfg, err := config.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return api.Config{}, err
+	}
+
+	values := make(map[string]interface{}, len(cfg.Values))
+	for key, value := range cfg.Values {
+		values[key] = value
+		if s, ok := value.(string); ok && IsSealed(s) {
+			if values[key], err = r.Open(key, s); err != nil {
+				return api.Config{}, err
+			}
+		}
+	}
+	cfg.Values = values
+
+	return cfg, nil
+}
@@ -0,0 +1,210 @@
+package sealed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+var (
+	oldKey = Key{ID: "2016-01", Secret: bytes.Repeat([]byte{1}, KeySize)}
+	newKey = Key{ID: "2016-02", Secret: bytes.Repeat([]byte{2}, KeySize)}
+)
+
+func TestSealAndOpen(t *testing.T) {
+	t.Parallel()
+
+	keyring, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := keyring.Seal("API_TOKEN", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(value, "sealed:v1:2016-02:") || strings.Contains(value, "hunter2") {
+		t.Errorf("Expected a value sealed with 2016-02, Got %s", value)
+	}
+
+	if opened, err := keyring.Open("API_TOKEN", value); err != nil || opened != "hunter2" {
+		t.Errorf("Expected hunter2, Got %s, %v", opened, err)
+	}
+
+	if _, err := keyring.Open("OTHER_TOKEN", value); err != (ErrInvalidValue{Key: "OTHER_TOKEN"}) {
+		t.Errorf("Expected a value moved to another key to fail, Got %v", err)
+	}
+
+	tampered := value[:len(value)-4] + "AAA="
+	if _, err := keyring.Open("API_TOKEN", tampered); err != (ErrInvalidValue{Key: "API_TOKEN"}) {
+		t.Errorf("Expected a tampered value to fail, Got %v", err)
+	}
+
+	oldOnly, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oldOnly.Open("API_TOKEN", value); err != (ErrUnknownKey{Key: "API_TOKEN", ID: "2016-02"}) {
+		t.Errorf("Expected an unknown key, Got %v", err)
+	}
+
+	if opened, err := keyring.Open("DEBUG", "true"); err != nil || opened != "true" {
+		t.Errorf("Expected plain values to be returned as is, Got %s, %v", opened, err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateKey("primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseKey(key.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(key, parsed) {
+		t.Errorf("Expected %#v, Got %#v", key, parsed)
+	}
+
+	secret := key.Encode()[len("primary:"):]
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if actual := fmt.Sprintf(format, key); strings.Contains(actual, secret) {
+			t.Errorf("Expected the secret to be hidden, Got %s", actual)
+		}
+	}
+
+	for _, s := range []string{"", "nosecret", "bad:id:" + secret, "short:c2hvcnQ="} {
+		if _, err := ParseKey(s); err != ErrInvalidKey {
+			t.Errorf("%s: Expected %v, Got %v", s, ErrInvalidKey, err)
+		}
+	}
+
+	if _, err := NewKeyring(); err != ErrNoKeys {
+		t.Errorf("Expected %v, Got %v", ErrNoKeys, err)
+	}
+}
+
+// fakeHTTPServer keeps an app's config values and records every change.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	values   map[string]interface{}
+	requests int
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.URL.Path != "/v2/apps/example-go/config/" {
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+		return
+	}
+
+	if req.Method == "POST" {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fmt.Println(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+			return
+		}
+		patch := api.Config{}
+		json.Unmarshal(body, &patch)
+		for key, value := range patch.Values {
+			f.values[key] = value
+		}
+		f.requests++
+		res.WriteHeader(http.StatusCreated)
+	}
+
+	out, _ := json.Marshal(api.Config{Values: f.values})
+	res.Write(out)
+}
+
+func TestSetListAndRotate(t *testing.T) {
+	t.Parallel()
+
+	handler := &fakeHTTPServer{values: map[string]interface{}{"DEBUG": "true"}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKeyring, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Set(deis, "example-go", oldKeyring, map[string]string{"API_TOKEN": "hunter2", "DSN": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if KeyID(handler.values["API_TOKEN"].(string)) != "2016-01" {
+		t.Errorf("Expected API_TOKEN to be sealed with 2016-01, Got %v", handler.values["API_TOKEN"])
+	}
+
+	keyring, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Set(deis, "example-go", keyring, map[string]string{"NEW_TOKEN": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rotation, err := Rotate(deis, "example-go", keyring, RotateOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `would reseal API_TOKEN (2016-01 -> 2016-02)
+would reseal DSN (2016-01 -> 2016-02)
+2 would reseal, 1 current
+`
+	if rotation.String() != expected {
+		t.Errorf("Expected %s, Got %s", expected, rotation.String())
+	}
+	if handler.requests != 2 {
+		t.Errorf("Expected no changes in a dry run, Got %d requests", handler.requests)
+	}
+
+	if _, err := Rotate(deis, "example-go", keyring, RotateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old key is no longer needed.
+	newKeyring, err := NewKeyring(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := List(deis, "example-go", newKeyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedValues := map[string]interface{}{"DEBUG": "true", "API_TOKEN": "hunter2", "DSN": "x", "NEW_TOKEN": "abc"}
+	if !reflect.DeepEqual(expectedValues, cfg.Values) {
+		t.Errorf("Expected %v, Got %v", expectedValues, cfg.Values)
+	}
+
+	if _, err := Rotate(deis, "example-go", oldKeyring, RotateOptions{}); err == nil {
+		t.Error("Expected rotating without the current key to fail")
+	}
+}
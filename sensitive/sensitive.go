@@ -0,0 +1,193 @@
+// Package sensitive provides methods for classifying config values that hold secrets and
+// masking them when they're displayed.
+//
+// A config value is sensitive if its key matches a built-in name pattern, such as
+// DATABASE_PASSWORD or STRIPE_SECRET_KEY, or if its value looks like a secret: a URL with a
+// password, or a long random string. User-defined rules are checked first and can mark keys as
+// sensitive or not.
+//
+// This example prints an app's config without leaking secrets into a CI log:
+//
+//    cfg, err := config.List(client, "example-go")
+//    if err != nil {
+//        log.Fatal(err)
+//    }
+//    for key, value := range sensitive.Masker{}.Values(cfg.Values) {
+//        fmt.Printf("%s=%s\n", key, value)
+//    }
+package sensitive
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/deis/controller-sdk-go/api"
+)
+
+const (
+	// Mask replaces sensitive values.
+	Mask = "********"
+
+	// minRandomLength is the shortest value considered a random secret.
+	minRandomLength = 20
+	// minEntropy is the Shannon entropy in bits per character above which a value is
+	// considered random.
+	minEntropy = 3.5
+)
+
+// namePattern matches the names of config variables that usually hold secrets. KEY only
+// matches as a whole word, so names like MONKEY or KEYBOARD_LAYOUT don't.
+var namePattern = regexp.MustCompile(`(?i)(PASSWORD|PASSWD|SECRET|TOKEN|(^|_)KEY(_|$)|DSN|CREDENTIAL|PRIVATE)`)
+
+// Rule classifies the config keys matching a pattern.
+type Rule struct {
+	Key *regexp.Regexp
+	// Sensitive is the classification of matching keys. Setting it to false marks keys as not
+	// sensitive, even if a built-in pattern or heuristic would match.
+	Sensitive bool
+}
+
+// Classifier decides which config values are sensitive.
+type Classifier struct {
+	// Rules are checked in order before the built-in patterns and heuristics. The first
+	// matching rule decides.
+	Rules []Rule
+}
+
+// Sensitive returns true if a config value is sensitive.
+func (c Classifier) Sensitive(key, value string) bool {
+	for _, rule := range c.Rules {
+		if rule.Key != nil && rule.Key.MatchString(key) {
+			return rule.Sensitive
+		}
+	}
+
+	return namePattern.MatchString(key) || hasCredentials(value) || random(value)
+}
+
+// hasCredentials returns true if a value is a URL with a password, such as
+// "postgres://user:password@db/example".
+func hasCredentials(value string) bool {
+	if !strings.Contains(value, "://") {
+		return false
+	}
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.User == nil {
+		return false
+	}
+	password, ok := u.User.Password()
+	return ok && password != ""
+}
+
+// random returns true if a value looks like a generated secret: long, without whitespace,
+// mixing letters and digits or cases, and with high entropy.
+func random(value string) bool {
+	if len(value) < minRandomLength || strings.IndexFunc(value, unicode.IsSpace) != -1 {
+		return false
+	}
+
+	var lower, upper, digit bool
+	counts := map[rune]int{}
+	for _, r := range value {
+		counts[r]++
+		lower = lower || unicode.IsLower(r)
+		upper = upper || unicode.IsUpper(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 2 {
+		return false
+	}
+
+	entropy := 0.0
+	length := float64(len([]rune(value)))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
+
+// Masker masks sensitive values for display.
+type Masker struct {
+	Classifier Classifier
+	// Reveal displays every value as is. Only set it when the output is seen by someone
+	// authorized to read the app's secrets.
+	Reveal bool
+}
+
+// Value returns a config value, or Mask if it's sensitive.
+func (m Masker) Value(key, value string) string {
+	if !m.Reveal && m.Classifier.Sensitive(key, value) {
+		return Mask
+	}
+	return value
+}
+
+// Change returns the old and new values of a changed key. Both are masked if either is
+// sensitive, so a value that stops looking like a secret isn't revealed. Empty values, such as
+// the old value of an added key, are left empty.
+func (m Masker) Change(key, before, after string) (string, string) {
+	if m.Reveal || (!m.Classifier.Sensitive(key, before) && !m.Classifier.Sensitive(key, after)) {
+		return before, after
+	}
+	return mask(before), mask(after)
+}
+
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return Mask
+}
+
+// Values returns config values as strings, with sensitive values masked.
+func (m Masker) Values(values map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(values))
+	for key, value := range values {
+		out[key] = m.Value(key, fmt.Sprint(value))
+	}
+	return out
+}
+
+// Config returns a copy of an app's config with sensitive values and the registry password
+// masked.
+func (m Masker) Config(cfg api.Config) api.Config {
+	if m.Reveal {
+		return cfg
+	}
+
+	if cfg.Values != nil {
+		values := make(map[string]interface{}, len(cfg.Values))
+		for key, value := range cfg.Values {
+			values[key] = value
+			if s := fmt.Sprint(value); m.Classifier.Sensitive(key, s) {
+				values[key] = Mask
+			}
+		}
+		cfg.Values = values
+	}
+
+	if cfg.Registry != nil {
+		registry := make(map[string]interface{}, len(cfg.Registry))
+		for key, value := range cfg.Registry {
+			registry[key] = value
+			if key == "password" {
+				registry[key] = Mask
+			}
+		}
+		cfg.Registry = registry
+	}
+
+	return cfg
+}
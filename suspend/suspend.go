@@ -0,0 +1,193 @@
+// Package suspend provides methods for parking idle apps by scaling them to zero.
+//
+// Suspend records the scale of every process type in an app label before scaling the app to
+// zero, and Resume scales the app back to the recorded scale and removes the label. Both are
+// idempotent: suspending a suspended app keeps the original record, and resuming an app which
+// isn't suspended does nothing.
+package suspend
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/appsettings"
+	"github.com/deis/controller-sdk-go/ps"
+)
+
+const (
+	// Label is the app label which records the scale of a suspended app, such as "web=2,worker=1".
+	Label = "suspended-scale"
+
+	// listLimit is the number of pods requested when counting an app's scale.
+	listLimit = 1000
+)
+
+// ErrInvalidLabel is returned when an app's suspended scale label can't be parsed.
+var ErrInvalidLabel = errors.New("The suspended scale label is invalid")
+
+// Suspend records the scale of an app's process types and scales every type to zero.
+// It returns the recorded scale. If the app is already suspended, the existing record is
+// kept, and both its process types and any running since are scaled to zero again.
+func Suspend(c *deis.Client, appID string) (map[string]int, error) {
+	scale, suspended, err := Suspended(c, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	running, err := current(c, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !suspended {
+		scale = running
+
+		// The scale is recorded before scaling down, so it isn't lost if scaling fails.
+		settings := api.AppSettings{Label: api.Labels{Label: Format(scale)}}
+		if _, err := appsettings.Set(c, appID, settings); err != nil && !deis.IsErrAPIMismatch(err) {
+			return nil, err
+		}
+	}
+
+	// A suspended app may run types that were added and scaled up since it was suspended.
+	zero := make(map[string]int, len(scale))
+	for procType := range scale {
+		zero[procType] = 0
+	}
+	for procType := range running {
+		zero[procType] = 0
+	}
+	if len(zero) == 0 {
+		return scale, nil
+	}
+
+	if err := ps.Scale(c, appID, zero); err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+
+	return scale, nil
+}
+
+// Resume scales a suspended app back to its recorded scale and removes the record.
+// It returns the restored scale, which is nil if the app wasn't suspended.
+func Resume(c *deis.Client, appID string) (map[string]int, error) {
+	scale, suspended, err := Suspended(c, appID)
+	if err != nil || !suspended {
+		return nil, err
+	}
+
+	if len(scale) > 0 {
+		if err := ps.Scale(c, appID, scale); err != nil && !deis.IsErrAPIMismatch(err) {
+			return nil, err
+		}
+	}
+
+	settings := api.AppSettings{Label: api.Labels{Label: nil}}
+	if _, err := appsettings.Set(c, appID, settings); err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+
+	return scale, nil
+}
+
+// Suspended returns the recorded scale of an app and whether the app is suspended.
+func Suspended(c *deis.Client, appID string) (map[string]int, bool, error) {
+	settings, err := appsettings.List(c, appID)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, false, err
+	}
+
+	value, ok := settings.Label[Label]
+	if !ok || value == nil {
+		return nil, false, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, false, ErrInvalidLabel
+	}
+
+	scale, err := Parse(s)
+	if err != nil {
+		return nil, false, err
+	}
+	return scale, true, nil
+}
+
+// Result is the outcome of suspending or resuming one app in bulk.
+type Result struct {
+	App string
+	// Scale is the recorded or restored scale of the app.
+	Scale map[string]int
+	Err   error
+}
+
+// SuspendAll suspends every app, continuing past failures. Results are in the order of apps.
+func SuspendAll(c *deis.Client, apps []string) []Result {
+	return bulk(c, apps, Suspend)
+}
+
+// ResumeAll resumes every app, continuing past failures. Results are in the order of apps.
+func ResumeAll(c *deis.Client, apps []string) []Result {
+	return bulk(c, apps, Resume)
+}
+
+func bulk(c *deis.Client, apps []string, op func(*deis.Client, string) (map[string]int, error)) []Result {
+	results := make([]Result, len(apps))
+	for i, app := range apps {
+		scale, err := op(c, app)
+		results[i] = Result{App: app, Scale: scale, Err: err}
+	}
+	return results
+}
+
+// Format formats a scale as a label value, such as "web=2,worker=1", sorted by process type.
+func Format(scale map[string]int) string {
+	var types []string
+	for procType := range scale {
+		types = append(types, procType)
+	}
+	sort.Strings(types)
+
+	parts := make([]string, len(types))
+	for i, procType := range types {
+		parts[i] = fmt.Sprintf("%s=%d", procType, scale[procType])
+	}
+	return strings.Join(parts, ",")
+}
+
+// Parse parses a label value created by Format.
+func Parse(value string) (map[string]int, error) {
+	scale := map[string]int{}
+	if value == "" {
+		return scale, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, ErrInvalidLabel
+		}
+		count, err := strconv.Atoi(kv[1])
+		if err != nil || count < 0 {
+			return nil, ErrInvalidLabel
+		}
+		scale[kv[0]] = count
+	}
+	return scale, nil
+}
+
+// current counts the pods of each process type with ps.Replicas, so terminating pods and the
+// old pods of a rollout aren't recorded.
+func current(c *deis.Client, appID string) (map[string]int, error) {
+	pods, _, err := ps.List(c, appID, listLimit)
+	if err != nil && !deis.IsErrAPIMismatch(err) {
+		return nil, err
+	}
+	return ps.Replicas(pods), nil
+}
@@ -0,0 +1,289 @@
+package suspend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+const podFixture string = `{"release": "v2", "type": "%s", "name": "%s-%d", "state": "up", "started": "2016-02-13T00:47:52"}`
+
+// fakeHTTPServer keeps the scale and labels of each app, and records every change.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	scale    map[string]map[string]int
+	labels   map[string]api.Labels
+	requests []string
+	// stale are pods listed along with the scaled ones, such as pods that are shutting down.
+	stale map[string][]string
+}
+
+func newFakeHTTPServer() *fakeHTTPServer {
+	return &fakeHTTPServer{
+		scale: map[string]map[string]int{
+			"example-go": {"web": 2, "worker": 1},
+			"idle-go":    {},
+		},
+		labels: map[string]api.Labels{"example-go": {"team": "core"}, "idle-go": {}},
+	}
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 4 || f.scale[parts[2]] == nil {
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+		return
+	}
+	app := parts[2]
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		fmt.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write(nil)
+		return
+	}
+	if req.Method != "GET" {
+		f.requests = append(f.requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body))
+	}
+
+	switch {
+	case parts[3] == "settings" && req.Method == "GET":
+		out, _ := json.Marshal(api.AppSettings{Label: f.labels[app]})
+		res.Write(out)
+	case parts[3] == "settings" && req.Method == "POST":
+		settings := api.AppSettings{}
+		json.Unmarshal(body, &settings)
+		for key, value := range settings.Label {
+			if value == nil {
+				delete(f.labels[app], key)
+			} else {
+				f.labels[app][key] = value
+			}
+		}
+		out, _ := json.Marshal(api.AppSettings{Label: f.labels[app]})
+		res.Write(out)
+	case parts[3] == "pods" && req.Method == "GET":
+		var pods []string
+		for procType, count := range f.scale[app] {
+			for i := 0; i < count; i++ {
+				pods = append(pods, fmt.Sprintf(podFixture, procType, procType, i))
+			}
+		}
+		pods = append(pods, f.stale[app]...)
+		res.Write([]byte(fmt.Sprintf(`{"count": %d, "results": [%s]}`, len(pods), strings.Join(pods, ","))))
+	case parts[3] == "scale" && req.Method == "POST":
+		scale := map[string]int{}
+		json.Unmarshal(body, &scale)
+		for procType, count := range scale {
+			f.scale[app][procType] = count
+		}
+		res.WriteHeader(http.StatusNoContent)
+		res.Write(nil)
+	default:
+		fmt.Printf("Unrecognized URL %s\n", req.URL)
+		res.WriteHeader(http.StatusNotFound)
+		res.Write(nil)
+	}
+}
+
+func TestSuspendResume(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"web": 2, "worker": 1}
+
+	for i := 0; i < 2; i++ {
+		scale, err := Suspend(deis, "example-go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, scale) {
+			t.Errorf("Expected %v, Got %v", expected, scale)
+		}
+	}
+
+	if label := handler.labels["example-go"][Label]; label != "web=2,worker=1" {
+		t.Errorf("Expected web=2,worker=1, Got %v", label)
+	}
+
+	zero := map[string]int{"web": 0, "worker": 0}
+	if !reflect.DeepEqual(zero, handler.scale["example-go"]) {
+		t.Errorf("Expected %v, Got %v", zero, handler.scale["example-go"])
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := Resume(deis, "example-go"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !reflect.DeepEqual(expected, handler.scale["example-go"]) {
+		t.Errorf("Expected %v, Got %v", expected, handler.scale["example-go"])
+	}
+
+	labels := api.Labels{"team": "core"}
+	if !reflect.DeepEqual(labels, handler.labels["example-go"]) {
+		t.Errorf("Expected %v, Got %v", labels, handler.labels["example-go"])
+	}
+
+	expectedRequests := []string{
+		`POST /v2/apps/example-go/settings/ {"label":{"suspended-scale":"web=2,worker=1"}}`,
+		`POST /v2/apps/example-go/scale/ {"web":0,"worker":0}`,
+		`POST /v2/apps/example-go/scale/ {"web":0,"worker":0}`,
+		`POST /v2/apps/example-go/scale/ {"web":2,"worker":1}`,
+		`POST /v2/apps/example-go/settings/ {"label":{"suspended-scale":null}}`,
+	}
+	if !reflect.DeepEqual(expectedRequests, handler.requests) {
+		t.Errorf("Expected %v, Got %v", expectedRequests, handler.requests)
+	}
+}
+
+func TestSuspendNewType(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Suspend(deis, "example-go"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A type added and scaled up while the app is suspended is scaled down with the others.
+	handler.mu.Lock()
+	handler.scale["example-go"]["clock"] = 1
+	handler.mu.Unlock()
+
+	scale, err := Suspend(deis, "example-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"web": 2, "worker": 1}
+	if !reflect.DeepEqual(expected, scale) {
+		t.Errorf("Expected %v, Got %v", expected, scale)
+	}
+
+	zero := map[string]int{"clock": 0, "web": 0, "worker": 0}
+	if !reflect.DeepEqual(zero, handler.scale["example-go"]) {
+		t.Errorf("Expected %v, Got %v", zero, handler.scale["example-go"])
+	}
+
+	request := `POST /v2/apps/example-go/scale/ {"clock":0,"web":0,"worker":0}`
+	if last := handler.requests[len(handler.requests)-1]; last != request {
+		t.Errorf("Expected %s, Got %s", request, last)
+	}
+}
+
+func TestSuspendIgnoresStalePods(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	handler.stale = map[string][]string{"example-go": {
+		`{"release": "v2", "type": "web", "name": "web-9", "state": "terminating", "started": "2016-02-13T00:47:52"}`,
+		`{"release": "v1", "type": "worker", "name": "worker-9", "state": "up", "started": "2016-02-13T00:47:52"}`,
+	}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scale, err := Suspend(deis, "example-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"web": 2, "worker": 1}
+	if !reflect.DeepEqual(expected, scale) {
+		t.Errorf("Expected %v, Got %v", expected, scale)
+	}
+}
+
+func TestSuspendAll(t *testing.T) {
+	t.Parallel()
+
+	handler := newFakeHTTPServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := SuspendAll(deis, []string{"example-go", "missing-go", "idle-go"})
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, Got %v", results)
+	}
+
+	if results[0].App != "example-go" || results[0].Err != nil || results[0].Scale["web"] != 2 {
+		t.Errorf("Expected example-go to be suspended, Got %v", results[0])
+	}
+	if results[1].App != "missing-go" || results[1].Err == nil {
+		t.Errorf("Expected missing-go to fail, Got %v", results[1])
+	}
+	if results[2].App != "idle-go" || results[2].Err != nil || len(results[2].Scale) != 0 {
+		t.Errorf("Expected idle-go to be suspended with no processes, Got %v", results[2])
+	}
+
+	results = ResumeAll(deis, []string{"example-go", "idle-go"})
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("Expected %s to resume, Got %v", result.App, result.Err)
+		}
+	}
+
+	if handler.scale["example-go"]["web"] != 2 {
+		t.Errorf("Expected 2 web processes, Got %d", handler.scale["example-go"]["web"])
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	scale, err := Parse(Format(map[string]int{"worker": 1, "web": 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]int{"web": 2, "worker": 1}; !reflect.DeepEqual(expected, scale) {
+		t.Errorf("Expected %v, Got %v", expected, scale)
+	}
+
+	for _, value := range []string{"web", "web=x", "=1", "web=-1"} {
+		if _, err := Parse(value); err != ErrInvalidLabel {
+			t.Errorf("Expected %v for %q, Got %v", ErrInvalidLabel, value, err)
+		}
+	}
+}
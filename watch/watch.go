@@ -0,0 +1,236 @@
+// Package watch provides a stream of events describing changes to an app's pods.
+//
+// The controller only returns a point-in-time list of pods, so Watch periodically lists an
+// app's pods and compares each listing to the last to produce events.
+//
+// This example prints the state changes of an app's pods:
+//
+//    for event := range watch.Watch(ctx, client, "example-go", watch.Options{}) {
+//        if event.Type == watch.Error {
+//            log.Println(event.Err)
+//            continue
+//        }
+//        fmt.Println(event)
+//    }
+package watch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+	"github.com/deis/controller-sdk-go/ps"
+)
+
+const (
+	// DefaultInterval is the time waited between listings of an app's pods.
+	DefaultInterval = 5 * time.Second
+
+	// listLimit is the number of pods requested when listing an app.
+	listLimit = 1000
+)
+
+// EventType is the kind of change an event describes.
+type EventType int
+
+const (
+	// PodAdded is sent when a pod appears.
+	PodAdded EventType = iota
+	// PodRemoved is sent when a pod disappears.
+	PodRemoved
+	// StateChanged is sent when a pod moves to a new state.
+	StateChanged
+	// ReleaseChanged is sent when the newest release running in the app changes.
+	ReleaseChanged
+	// Error is sent when listing the app's pods fails. Watching continues afterwards.
+	Error
+)
+
+func (t EventType) String() string {
+	switch t {
+	case PodAdded:
+		return "added"
+	case PodRemoved:
+		return "removed"
+	case StateChanged:
+		return "state changed"
+	case ReleaseChanged:
+		return "release changed"
+	default:
+		return "error"
+	}
+}
+
+// Event is a change to an app's pods.
+type Event struct {
+	Type EventType
+	App  string
+	// Pod is the pod that changed. For removed pods, it is the pod as last listed.
+	// It is empty for ReleaseChanged and Error events.
+	Pod api.Pods
+	// OldState and NewState are the states of the pod before and after the change.
+	OldState api.PodState
+	NewState api.PodState
+	// OldRelease and NewRelease are the app releases before and after a ReleaseChanged event.
+	OldRelease string
+	NewRelease string
+	// Err is the error of an Error event.
+	Err error
+	// Time is when the change was observed.
+	Time time.Time
+}
+
+// String displays the Event in a readable format.
+func (e Event) String() string {
+	switch e.Type {
+	case PodAdded, PodRemoved:
+		return fmt.Sprintf("%s: pod %s %s (%s)", e.App, e.Pod.Name, e.Type, e.Pod.State)
+	case StateChanged:
+		return fmt.Sprintf("%s: pod %s %s to %s", e.App, e.Pod.Name, e.OldState, e.NewState)
+	case ReleaseChanged:
+		return fmt.Sprintf("%s: release changed from %s to %s", e.App, e.OldRelease, e.NewRelease)
+	default:
+		return fmt.Sprintf("%s: %v", e.App, e.Err)
+	}
+}
+
+// Options controls how an app is watched.
+type Options struct {
+	// Interval is the time waited between listings. If zero, DefaultInterval is used.
+	Interval time.Duration
+}
+
+// Watch lists an app's pods every interval and sends an event for every change. The first
+// listing sends a PodAdded event for every existing pod. The channel is closed once the
+// context is done.
+func Watch(ctx context.Context, c *deis.Client, appID string, opts Options) <-chan Event {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		var last api.PodsList
+		for {
+			pods, _, err := ps.List(c, appID, listLimit)
+			if err != nil && !deis.IsErrAPIMismatch(err) {
+				if !send(ctx, events, Event{Type: Error, App: appID, Err: err, Time: time.Now()}) {
+					return
+				}
+			} else {
+				for _, event := range Diff(appID, last, pods) {
+					if !send(ctx, events, event) {
+						return
+					}
+				}
+				last = pods
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(opts.Interval):
+			}
+		}
+	}()
+
+	return events
+}
+
+func send(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case events <- event:
+		return true
+	}
+}
+
+// Diff compares two listings of an app's pods and returns the events between them.
+// Pod events are sorted by pod name, followed by any release change.
+func Diff(appID string, previous, current api.PodsList) []Event {
+	now := time.Now()
+	before := byName(previous)
+	after := byName(current)
+
+	var names []string
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var events []Event
+	for _, name := range names {
+		o, existed := before[name]
+		n, exists := after[name]
+
+		switch {
+		case !existed:
+			state := api.ParsePodState(n.State)
+			events = append(events, Event{Type: PodAdded, App: appID, Pod: n, NewState: state, Time: now})
+		case !exists:
+			state := api.ParsePodState(o.State)
+			events = append(events, Event{Type: PodRemoved, App: appID, Pod: o, OldState: state, Time: now})
+		case o.State != n.State:
+			events = append(events, Event{
+				Type:     StateChanged,
+				App:      appID,
+				Pod:      n,
+				OldState: api.ParsePodState(o.State),
+				NewState: api.ParsePodState(n.State),
+				Time:     now,
+			})
+		}
+	}
+
+	oldRelease, newRelease := Newest(previous), Newest(current)
+	if oldRelease != "" && newRelease != "" && oldRelease != newRelease {
+		events = append(events, Event{
+			Type:       ReleaseChanged,
+			App:        appID,
+			OldRelease: oldRelease,
+			NewRelease: newRelease,
+			Time:       now,
+		})
+	}
+
+	return events
+}
+
+// Newest returns the newest release, such as "v3", running in a list of pods.
+// It returns an empty string if there are no pods.
+func Newest(pods api.PodsList) string {
+	newest := ""
+	newestVersion := -1
+	for _, pod := range pods {
+		version, err := strconv.Atoi(strings.TrimPrefix(pod.Release, "v"))
+		if err != nil {
+			continue
+		}
+		if version > newestVersion {
+			newest, newestVersion = pod.Release, version
+		}
+	}
+	return newest
+}
+
+func byName(pods api.PodsList) map[string]api.Pods {
+	out := make(map[string]api.Pods, len(pods))
+	for _, pod := range pods {
+		out[pod.Name] = pod
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	deis "github.com/deis/controller-sdk-go"
+	"github.com/deis/controller-sdk-go/api"
+)
+
+var podsFixtures = []string{`
+{
+    "count": 2,
+    "next": null,
+    "previous": null,
+    "results": [
+        {"release": "v2", "type": "web", "name": "web-1", "state": "up", "started": "2016-02-13T00:47:52"},
+        {"release": "v2", "type": "web", "name": "web-2", "state": "starting", "started": "2016-02-13T00:47:52"}
+    ]
+}`, `
+{
+    "count": 2,
+    "next": null,
+    "previous": null,
+    "results": [
+        {"release": "v2", "type": "web", "name": "web-2", "state": "up", "started": "2016-02-13T00:47:52"},
+        {"release": "v3", "type": "web", "name": "web-3", "state": "starting", "started": "2016-02-13T00:47:52"}
+    ]
+}`}
+
+// fakeHTTPServer serves the pod fixtures in order, failing once between them.
+type fakeHTTPServer struct {
+	mu    sync.Mutex
+	polls int
+}
+
+func (f *fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", deis.APIVersion)
+
+	if req.URL.Path == "/v2/apps/example-go/pods/" && req.Method == "GET" {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.polls++
+		switch f.polls {
+		case 1:
+			res.Write([]byte(podsFixtures[0]))
+		case 2:
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write(nil)
+		default:
+			res.Write([]byte(podsFixtures[1]))
+		}
+		return
+	}
+
+	fmt.Printf("Unrecognized URL %s\n", req.URL)
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(&handler)
+	defer server.Close()
+
+	deis, err := deis.New(false, server.URL, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := Watch(ctx, deis, "example-go", Options{Interval: time.Millisecond})
+
+	expected := []string{
+		"example-go: pod web-1 added (up)",
+		"example-go: pod web-2 added (starting)",
+		"example-go: Internal Server Error",
+		"example-go: pod web-1 removed (up)",
+		"example-go: pod web-2 starting to up",
+		"example-go: pod web-3 added (starting)",
+		"example-go: release changed from v2 to v3",
+	}
+
+	var actual []string
+	for len(actual) < len(expected) {
+		select {
+		case event := <-events:
+			actual = append(actual, event.String())
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for events, Got %v", actual)
+		}
+	}
+	cancel()
+
+	// The channel is closed once the watch is cancelled.
+	for range events {
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	previous := api.PodsList{
+		{Release: "v9", Name: "web-a", State: "up"},
+		{Release: "v9", Name: "web-b", State: "up"},
+	}
+	current := api.PodsList{
+		{Release: "v9", Name: "web-b", State: "crashed"},
+		{Release: "v10", Name: "web-c", State: "bogus"},
+	}
+
+	events := Diff("example-go", previous, current)
+
+	expected := []Event{
+		{Type: PodRemoved, App: "example-go", Pod: previous[0], OldState: api.PodUp},
+		{Type: StateChanged, App: "example-go", Pod: current[0], OldState: api.PodUp, NewState: api.PodCrashed},
+		{Type: PodAdded, App: "example-go", Pod: current[1], NewState: api.PodUnknown},
+		{Type: ReleaseChanged, App: "example-go", OldRelease: "v9", NewRelease: "v10"},
+	}
+
+	for i := range events {
+		events[i].Time = time.Time{}
+	}
+
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("Expected %v, Got %v", expected, events)
+	}
+
+	if events := Diff("example-go", current, current); len(events) != 0 {
+		t.Errorf("Expected no events, Got %v", events)
+	}
+}